@@ -3,27 +3,31 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"health-checker/internal/collectors"
 	"health-checker/internal/configs"
 	"health-checker/internal/handlers"
 	"health-checker/internal/services"
+	"health-checker/internal/storage"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"runtime"
 	"syscall"
 	"time"
 )
 
 func main() {
-	if runtime.GOOS != "windows" {
-		slog.Info("only windows supported")
+	cfg, err := configs.GetCheckerCfg()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
 		os.Exit(1)
 	}
 
-	cfg := configs.GetCheckerCfg()
-
 	if cfg.DebugMode {
 		opts := &slog.HandlerOptions{
 			Level: slog.LevelDebug,
@@ -34,16 +38,30 @@ func main() {
 		slog.Debug("debug mode enabled")
 	}
 
-	if cfg.Interval < 0 {
-		slog.Error("incorrect interval, please specify >= 0")
-		return
-	}
-
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	monitor := services.NewMonitor()
-	monitor.Start(ctx, cfg)
+	c := collectors.Default(cfg)
+	if len(c) == 0 {
+		slog.Info("only windows and linux supported")
+		os.Exit(1)
+	}
+
+	monitor := services.NewMonitor(cfg, c...)
+	if cfg.StorageDir != "" {
+		store, err := storage.Open(cfg.StorageDir, cfg.StorageRawSamples)
+		if err != nil {
+			slog.Error("storage is unavailable", "error", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+
+		monitor.SetStore(store)
+	}
+	if n := services.NewNotifier(cfg); n.Enabled() {
+		go n.Run(ctx, monitor.Subscribe(100))
+	}
+	monitor.Start(ctx)
 
 	address := cfg.Address + ":" + cfg.Port
 
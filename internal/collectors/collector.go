@@ -0,0 +1,20 @@
+package collectors
+
+import (
+	"context"
+	"health-checker/internal/models"
+	"time"
+)
+
+// Collector is a source of readings polled by services.Monitor.
+type Collector interface {
+	// Name returns the metric the collector reports, e.g. models.MetricCPU.
+	Name() string
+	Sample(ctx context.Context) ([]models.Reading, error)
+}
+
+// Scheduled is implemented by collectors that are polled on their own
+// interval instead of the check interval.
+type Scheduled interface {
+	Interval() time.Duration
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package collectors
+
+import "health-checker/internal/configs"
+
+// Default returns the procfs collectors.
+func Default(cfg configs.Checker) []Collector {
+	return []Collector{
+		NewProcCPU(cfg.ProcRoot),
+		NewProcRAM(cfg.ProcRoot),
+		NewProcNet(cfg.ProcRoot, cfg.SysRoot, NameFilter{Include: cfg.NetInclude, Exclude: cfg.NetExclude}),
+		NewProcDisk(cfg.ProcRoot),
+		NewStatfsDiskSpace(cfg.ProcRoot, cfg.HostRoot, cfg.DiskFreeInterval),
+	}
+}
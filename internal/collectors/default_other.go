@@ -0,0 +1,10 @@
+//go:build !windows && !linux
+
+package collectors
+
+import "health-checker/internal/configs"
+
+// Default returns the collectors available on the current platform.
+func Default(_ configs.Checker) []Collector {
+	return nil
+}
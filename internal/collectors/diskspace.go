@@ -0,0 +1,87 @@
+package collectors
+
+import (
+	"bufio"
+	"health-checker/internal/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// volumeReading returns the free space of volume in percent of its size.
+func volumeReading(volume string, free, size float64) models.Reading {
+	return models.Reading{
+		Metric: models.MetricDiskFree,
+		Device: volume,
+		Value:  free / size * 100,
+		Unit:   models.UnitPercent,
+		Raw: map[string]float64{
+			"free_bytes": free,
+			"size_bytes": size,
+		},
+	}
+}
+
+// appendFullest appends the host-wide reading of the volume readings, which
+// is the reading of the volume with the least free space in percent.
+func appendFullest(readings []models.Reading) []models.Reading {
+	if len(readings) == 0 {
+		return nil
+	}
+
+	fullest := readings[0]
+	for _, r := range readings[1:] {
+		if r.Value < fullest.Value {
+			fullest = r
+		}
+	}
+
+	fullest.Device = ""
+	return append(readings, fullest)
+}
+
+// readMounts returns the mount points of the block devices listed in a
+// mounts file, a device mounted several times is listed once.
+func readMounts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+
+		mounts = append(mounts, unescapeMount(fields[1]))
+	}
+
+	return mounts, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes of spaces and tabs in a mount point.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package collectors
+
+import (
+	"health-checker/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadMounts(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "mounts", `sysfs /sys sysfs rw,nosuid 0 0
+proc /proc proc rw,nosuid 0 0
+/dev/sda2 / ext4 rw,relatime 0 0
+tmpfs /run tmpfs rw 0 0
+/dev/sdb1 /var/lib/my\040data xfs rw 0 0
+/dev/sda2 /var/lib/docker ext4 rw,relatime 0 0
+`)
+
+	mounts, err := readMounts(root + "/mounts")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/", "/var/lib/my data"}, mounts)
+}
+
+func Test_AppendFullest(t *testing.T) {
+	readings := appendFullest([]models.Reading{
+		volumeReading("C:", 50, 100),
+		volumeReading("D:", 5, 100),
+	})
+
+	require.Len(t, readings, 3)
+	assert.Empty(t, readings[2].Device)
+	assert.Equal(t, 5.0, readings[2].Value)
+	assert.Equal(t, 5.0, readings[2].Raw["free_bytes"])
+}
@@ -0,0 +1,31 @@
+package collectors
+
+import "path"
+
+// NameFilter selects devices by name with the shell patterns of path.Match.
+type NameFilter struct {
+	// Include selects the matching names, every name when empty.
+	Include []string
+	// Exclude drops the matching names, it takes precedence over Include.
+	Exclude []string
+}
+
+// Match reports whether name is selected by the filter. Malformed patterns
+// never match, they are rejected when the configuration is validated.
+func (f NameFilter) Match(name string) bool {
+	for _, pattern := range f.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
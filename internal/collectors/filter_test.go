@@ -0,0 +1,29 @@
+package collectors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NameFilter_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter NameFilter
+		device string
+		want   bool
+	}{
+		{"empty filter", NameFilter{}, "eth0", true},
+		{"included", NameFilter{Include: []string{"eth*"}}, "eth0", true},
+		{"not included", NameFilter{Include: []string{"eth*"}}, "wlan0", false},
+		{"excluded", NameFilter{Exclude: []string{"docker*"}}, "docker0", false},
+		{"exclude wins", NameFilter{Include: []string{"*"}, Exclude: []string{"eth1"}}, "eth1", false},
+		{"malformed pattern", NameFilter{Include: []string{"["}}, "eth0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.device))
+		})
+	}
+}
@@ -0,0 +1,589 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"health-checker/internal/models"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcCPU reports processor utilization in percent from /proc/stat, for
+// every core and for the whole processor. The first sample only stores the
+// counters and produces no readings.
+type ProcCPU struct {
+	procRoot string
+	prev     map[string]cpuTimes
+}
+
+type cpuTimes struct {
+	idle  uint64
+	total uint64
+}
+
+func NewProcCPU(procRoot string) *ProcCPU {
+	return &ProcCPU{procRoot: procRoot}
+}
+
+func (c *ProcCPU) Name() string {
+	return models.MetricCPU
+}
+
+func (c *ProcCPU) Sample(_ context.Context) ([]models.Reading, error) {
+	cur, err := readCPUTimes(filepath.Join(c.procRoot, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	prev := c.prev
+	c.prev = cur
+
+	cores := make([]string, 0, len(cur))
+	for name := range cur {
+		if name != "" {
+			cores = append(cores, name)
+		}
+	}
+	sort.Slice(cores, func(i, j int) bool {
+		return models.LessDevice(cores[i], cores[j])
+	})
+
+	// the cores are reported before the whole processor, so that the zone
+	// of the processor accounts for them
+	var readings []models.Reading
+	for _, name := range append(cores, "") {
+		p, ok := prev[name]
+		t := cur[name]
+		if !ok || p.total == 0 || t.total <= p.total {
+			continue
+		}
+
+		readings = append(readings, models.Reading{
+			Metric: models.MetricCPU,
+			Device: name,
+			Value:  (1.0 - float64(t.idle-p.idle)/float64(t.total-p.total)) * 100,
+			Unit:   models.UnitPercent,
+			Raw: map[string]float64{
+				"idle_jiffies":  float64(t.idle - p.idle),
+				"total_jiffies": float64(t.total - p.total),
+			},
+		})
+	}
+
+	return readings, nil
+}
+
+// readCPUTimes returns the counters of every core keyed by the core number
+// and the counters of the whole processor under the empty key.
+func readCPUTimes(path string) (map[string]cpuTimes, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	times := make(map[string]cpuTimes)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		var t cpuTimes
+		for i, field := range fields[1:] {
+			// guest and guest_nice are already accounted in user and nice
+			if i >= 8 {
+				break
+			}
+
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			// idle and iowait
+			if i == 3 || i == 4 {
+				t.idle += v
+			}
+			t.total += v
+		}
+		times[strings.TrimPrefix(fields[0], "cpu")] = t
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := times[""]; !ok {
+		return nil, errors.New("no processor data")
+	}
+	return times, nil
+}
+
+// ProcRAM reports used memory in percent from /proc/meminfo. Memory that
+// can be reclaimed without swapping, as counted by MemAvailable, is not used.
+type ProcRAM struct {
+	procRoot string
+}
+
+func NewProcRAM(procRoot string) *ProcRAM {
+	return &ProcRAM{procRoot: procRoot}
+}
+
+func (r *ProcRAM) Name() string {
+	return models.MetricRAM
+}
+
+func (r *ProcRAM) Sample(_ context.Context) ([]models.Reading, error) {
+	info, err := readMemInfo(filepath.Join(r.procRoot, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	total, available := info["MemTotal"], info["MemAvailable"]
+	if total == 0 {
+		return nil, errors.New("no memory data")
+	}
+
+	// meminfo reports kilobytes
+	return []models.Reading{memReading(memory{
+		total:       float64(total * 1024),
+		available:   float64(available * 1024),
+		swapTotal:   float64(info["SwapTotal"] * 1024),
+		swapFree:    float64(info["SwapFree"] * 1024),
+		commit:      float64(info["Committed_AS"] * 1024),
+		commitLimit: float64(info["CommitLimit"] * 1024),
+	})}, nil
+}
+
+// memory holds the memory counters in bytes.
+type memory struct {
+	total       float64
+	available   float64
+	swapTotal   float64
+	swapFree    float64
+	commit      float64
+	commitLimit float64
+}
+
+// memReading returns the used memory in percent of the total memory.
+func memReading(m memory) models.Reading {
+	used := m.total - m.available
+
+	return models.Reading{
+		Metric: models.MetricRAM,
+		Value:  used / m.total * 100,
+		Unit:   models.UnitPercent,
+		Raw: map[string]float64{
+			"used_bytes":         used,
+			"available_bytes":    m.available,
+			"total_bytes":        m.total,
+			"swap_used_bytes":    m.swapTotal - m.swapFree,
+			"swap_total_bytes":   m.swapTotal,
+			"commit_bytes":       m.commit,
+			"commit_limit_bytes": m.commitLimit,
+		},
+	}
+}
+
+// readMemInfo returns the values of /proc/meminfo in kilobytes.
+func readMemInfo(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		v, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		info[key] = v
+	}
+
+	return info, scanner.Err()
+}
+
+// ProcNet reports utilization of every physical network interface selected
+// by the filter in percent from /proc/net/dev and /sys/class/net/*/speed,
+// followed by the utilization of the busiest one. Without any such
+// interface, e.g. in a container with a veth interface only, the utilization
+// is reported as zero.
+type ProcNet struct {
+	procRoot string
+	sysRoot  string
+	filter   NameFilter
+	now      func() time.Time
+	// warned is set once the missing interfaces are logged.
+	warned bool
+
+	prev     map[string]netCounters
+	prevTime time.Time
+}
+
+func NewProcNet(procRoot, sysRoot string, filter NameFilter) *ProcNet {
+	return &ProcNet{procRoot: procRoot, sysRoot: sysRoot, filter: filter, now: time.Now}
+}
+
+func (n *ProcNet) Name() string {
+	return models.MetricNet
+}
+
+func (n *ProcNet) Sample(_ context.Context) ([]models.Reading, error) {
+	ifaces, err := physicalInterfaces(filepath.Join(n.sysRoot, "class", "net"), n.filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(ifaces) == 0 && !n.warned {
+		slog.Warn("no physical network interface is selected, net utilization is reported as 0", "sys_root", n.sysRoot)
+	}
+	n.warned = len(ifaces) == 0
+
+	counters, err := readNetDev(filepath.Join(n.procRoot, "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+
+	now := n.now()
+	prev, prevTime := n.prev, n.prevTime
+	n.prev, n.prevTime = counters, now
+	if prevTime.IsZero() || !now.After(prevTime) {
+		return nil, nil
+	}
+	elapsed := now.Sub(prevTime).Seconds()
+
+	if len(ifaces) == 0 {
+		return []models.Reading{idleNetReading()}, nil
+	}
+
+	var readings []models.Reading
+	for _, iface := range ifaces {
+		c, ok := counters[iface.name]
+		p, seen := prev[iface.name]
+		if !ok || !seen || c.rx < p.rx || c.tx < p.tx {
+			continue
+		}
+
+		rx := float64(c.rx-p.rx) / elapsed
+		tx := float64(c.tx-p.tx) / elapsed
+		readings = append(readings, netReading(iface.name, rx, tx, iface.speed))
+	}
+
+	return appendBusiest(readings), nil
+}
+
+// idleNetReading returns the host-wide reading of a network without any
+// selected interface.
+func idleNetReading() models.Reading {
+	return models.Reading{Metric: models.MetricNet, Unit: models.UnitPercent}
+}
+
+// netReading returns the utilization of an interface receiving rx and
+// sending tx bytes per second over a link of speed bits per second.
+func netReading(iface string, rx, tx, speed float64) models.Reading {
+	return models.Reading{
+		Metric: models.MetricNet,
+		Device: iface,
+		Value:  8 * (rx + tx) / speed * 100,
+		Unit:   models.UnitPercent,
+		Raw: map[string]float64{
+			"bytes_per_second":    rx + tx,
+			"rx_bytes_per_second": rx,
+			"tx_bytes_per_second": tx,
+			"rx_percent":          8 * rx / speed * 100,
+			"tx_percent":          8 * tx / speed * 100,
+			"bandwidth_bps":       speed,
+		},
+	}
+}
+
+// appendBusiest appends the host-wide reading of the per-device readings,
+// which is the reading of the busiest device.
+func appendBusiest(readings []models.Reading) []models.Reading {
+	if len(readings) == 0 {
+		return nil
+	}
+
+	busiest := readings[0]
+	for _, r := range readings[1:] {
+		if r.Value > busiest.Value {
+			busiest = r
+		}
+	}
+
+	busiest.Device = ""
+	return append(readings, busiest)
+}
+
+type netInterface struct {
+	name string
+	// speed is the link speed in bits per second.
+	speed float64
+}
+
+// physicalInterfaces returns the interfaces selected by filter that are
+// backed by a device with a known link speed, it fails only when classNet
+// can not be read.
+func physicalInterfaces(classNet string, filter NameFilter) ([]netInterface, error) {
+	entries, err := os.ReadDir(classNet)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	var ifaces []netInterface
+	for _, name := range names {
+		if !filter.Match(name) {
+			continue
+		}
+		if _, err = os.Stat(filepath.Join(classNet, name, "device")); err != nil {
+			continue
+		}
+
+		raw, err := os.ReadFile(filepath.Join(classNet, name, "speed"))
+		if err != nil {
+			continue
+		}
+
+		speed, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+		if err != nil || speed <= 0 {
+			continue
+		}
+
+		// speed is reported in Mbit/s
+		ifaces = append(ifaces, netInterface{name: name, speed: float64(speed) * 1000 * 1000})
+	}
+
+	return ifaces, nil
+}
+
+type netCounters struct {
+	rx uint64
+	tx uint64
+}
+
+func readNetDev(path string) (map[string]netCounters, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := make(map[string]netCounters)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(value)
+		if len(fields) < 9 {
+			continue
+		}
+
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		counters[strings.TrimSpace(name)] = netCounters{rx: rx, tx: tx}
+	}
+
+	return counters, scanner.Err()
+}
+
+// ProcDisk reports the busy time of every physical disk and partition in
+// percent from /proc/diskstats, followed by the busy time of the busiest
+// disk. The readings also carry the throughput, IOPS and queue length.
+type ProcDisk struct {
+	procRoot string
+	now      func() time.Time
+
+	prev     map[string]diskStats
+	prevTime time.Time
+}
+
+func NewProcDisk(procRoot string) *ProcDisk {
+	return &ProcDisk{procRoot: procRoot, now: time.Now}
+}
+
+func (d *ProcDisk) Name() string {
+	return models.MetricDisk
+}
+
+func (d *ProcDisk) Sample(_ context.Context) ([]models.Reading, error) {
+	stats, err := readDiskStats(filepath.Join(d.procRoot, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, errors.New("no disk data")
+	}
+
+	now := d.now()
+	prev, prevTime := d.prev, d.prevTime
+	d.prev, d.prevTime = stats, now
+	if prevTime.IsZero() || !now.After(prevTime) {
+		return nil, nil
+	}
+	elapsed := now.Sub(prevTime)
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var readings []models.Reading
+	for _, name := range names {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		delta, ok := stats[name].sub(p)
+		if !ok {
+			continue
+		}
+
+		readings = append(readings, delta.reading(name, elapsed))
+	}
+
+	return appendBusiest(readings), nil
+}
+
+type diskStats struct {
+	reads          uint64
+	sectorsRead    uint64
+	writes         uint64
+	sectorsWritten uint64
+	// ioTicks is the time spent doing I/O in milliseconds.
+	ioTicks uint64
+	// weightedTicks is the time spent doing I/O multiplied by the number of
+	// requests in flight in milliseconds.
+	weightedTicks uint64
+}
+
+// sub returns the counters accumulated since prev, false when a counter
+// has been reset.
+func (s diskStats) sub(prev diskStats) (diskStats, bool) {
+	if s.reads < prev.reads || s.sectorsRead < prev.sectorsRead ||
+		s.writes < prev.writes || s.sectorsWritten < prev.sectorsWritten ||
+		s.ioTicks < prev.ioTicks || s.weightedTicks < prev.weightedTicks {
+		return diskStats{}, false
+	}
+
+	return diskStats{
+		reads:          s.reads - prev.reads,
+		sectorsRead:    s.sectorsRead - prev.sectorsRead,
+		writes:         s.writes - prev.writes,
+		sectorsWritten: s.sectorsWritten - prev.sectorsWritten,
+		ioTicks:        s.ioTicks - prev.ioTicks,
+		weightedTicks:  s.weightedTicks - prev.weightedTicks,
+	}, true
+}
+
+// reading returns the reading of device from the counters accumulated
+// during elapsed.
+func (s diskStats) reading(device string, elapsed time.Duration) models.Reading {
+	// diskstats counts sectors of 512 bytes regardless of the device
+	const sectorSize = 512
+
+	ms := float64(elapsed) / float64(time.Millisecond)
+	seconds := elapsed.Seconds()
+
+	return models.Reading{
+		Metric: models.MetricDisk,
+		Device: device,
+		Value:  float64(s.ioTicks) / ms * 100,
+		Unit:   models.UnitPercent,
+		Raw: map[string]float64{
+			"read_bytes_per_second":  float64(s.sectorsRead*sectorSize) / seconds,
+			"write_bytes_per_second": float64(s.sectorsWritten*sectorSize) / seconds,
+			"reads_per_second":       float64(s.reads) / seconds,
+			"writes_per_second":      float64(s.writes) / seconds,
+			"queue_length":           float64(s.weightedTicks) / ms,
+			"io_ms":                  float64(s.ioTicks),
+			"elapsed_ms":             ms,
+		},
+	}
+}
+
+// virtualDisks are device name prefixes that are not physical disks.
+var virtualDisks = []string{"loop", "ram", "zram", "dm-", "md", "sr", "fd"}
+
+// readDiskStats returns the statistics of physical disks and their
+// partitions, virtual devices are skipped.
+func readDiskStats(path string) (map[string]diskStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := make(map[string]diskStats)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 || isVirtualDisk(fields[2]) {
+			continue
+		}
+
+		var values [11]uint64
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[3+i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		stats[fields[2]] = diskStats{
+			reads:          values[0],
+			sectorsRead:    values[2],
+			writes:         values[4],
+			sectorsWritten: values[6],
+			ioTicks:        values[9],
+			weightedTicks:  values[10],
+		}
+	}
+
+	return stats, scanner.Err()
+}
+
+func isVirtualDisk(name string) bool {
+	for _, prefix := range virtualDisks {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
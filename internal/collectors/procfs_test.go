@@ -0,0 +1,282 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"health-checker/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// fakeClock returns a clock that advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func Test_ProcCPU_Sample(t *testing.T) {
+	root := t.TempDir()
+	c := NewProcCPU(root)
+
+	writeFile(t, root, "stat", "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n")
+	readings, err := c.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+
+	// 250 busy and 750 idle jiffies since the previous sample
+	writeFile(t, root, "stat", "cpu  300 0 150 1400 150 0 0 0 50 0\ncpu0 300 0 150 1400 150 0 0 0 50 0\n")
+	readings, err = c.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, models.MetricCPU, readings[1].Metric)
+	assert.Empty(t, readings[1].Device)
+	assert.InDelta(t, 25.0, readings[1].Value, 0.001)
+}
+
+func Test_ProcCPU_SampleCores(t *testing.T) {
+	root := t.TempDir()
+	c := NewProcCPU(root)
+
+	writeFile(t, root, "stat", "cpu  200 0 0 800 0 0 0 0\n"+
+		"cpu0 100 0 0 400 0 0 0 0\ncpu1 100 0 0 400 0 0 0 0\n"+
+		"intr 1 2 3\n")
+	_, err := c.Sample(context.Background())
+	require.NoError(t, err)
+
+	// core 0 fully busy, core 1 idle
+	writeFile(t, root, "stat", "cpu  300 0 0 900 0 0 0 0\n"+
+		"cpu0 200 0 0 400 0 0 0 0\ncpu1 100 0 0 500 0 0 0 0\n"+
+		"intr 1 2 3\n")
+	readings, err := c.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 3)
+
+	assert.Equal(t, "0", readings[0].Device)
+	assert.InDelta(t, 100.0, readings[0].Value, 0.001)
+	assert.Equal(t, "1", readings[1].Device)
+	assert.InDelta(t, 0.0, readings[1].Value, 0.001)
+	assert.Empty(t, readings[2].Device)
+	assert.InDelta(t, 50.0, readings[2].Value, 0.001)
+}
+
+func Test_ProcRAM_Sample(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "meminfo", `MemTotal:       16000000 kB
+MemFree:         1000000 kB
+MemAvailable:    4000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+CommitLimit:    10000000 kB
+Committed_AS:    9000000 kB
+`)
+
+	readings, err := NewProcRAM(root).Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 1)
+	assert.Equal(t, models.MetricRAM, readings[0].Metric)
+	assert.InDelta(t, 75.0, readings[0].Value, 0.001)
+	assert.Equal(t, models.UnitPercent, readings[0].Unit)
+	assert.Equal(t, map[string]float64{
+		"used_bytes":         12288000000,
+		"available_bytes":    4096000000,
+		"total_bytes":        16384000000,
+		"swap_used_bytes":    512000000,
+		"swap_total_bytes":   2048000000,
+		"commit_bytes":       9216000000,
+		"commit_limit_bytes": 10240000000,
+	}, readings[0].Raw)
+}
+
+func Test_ProcRAM_SampleNoData(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "meminfo", "")
+
+	_, err := NewProcRAM(root).Sample(context.Background())
+	assert.Error(t, err)
+}
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: %d       0    0    0    0     0          0         0 %d       0    0    0    0     0       0          0
+  eth0: %d       0    0    0    0     0          0         0 %d       0    0    0    0     0       0          0
+  eth1: %d       0    0    0    0     0          0         0 %d       0    0    0    0     0       0          0
+`
+
+// writeInterfaces creates eth0 at 100 Mbit/s, eth1 at 1 Gbit/s and the
+// loopback interface in sys.
+func writeInterfaces(t *testing.T, sys string) {
+	t.Helper()
+
+	writeFile(t, sys, "class/net/lo/speed", "10000\n")
+	writeFile(t, sys, "class/net/eth0/device", "")
+	writeFile(t, sys, "class/net/eth0/speed", "100\n")
+	writeFile(t, sys, "class/net/eth1/device", "")
+	writeFile(t, sys, "class/net/eth1/speed", "1000\n")
+}
+
+func Test_ProcNet_Sample(t *testing.T) {
+	proc, sys := t.TempDir(), t.TempDir()
+	writeInterfaces(t, sys)
+
+	n := NewProcNet(proc, sys, NameFilter{})
+	n.now = fakeClock(time.Second)
+
+	writeFile(t, proc, "net/dev", fmt.Sprintf(netDev, 0, 0, 1000, 1000, 0, 0))
+	readings, err := n.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+
+	// eth0 receives 3 MB/s and sends 2 MB/s on a 100 Mbit/s link, eth1
+	// receives 25 MB/s on a 1 Gbit/s link, loopback traffic is ignored
+	writeFile(t, proc, "net/dev", fmt.Sprintf(netDev, 1000000000, 1000000000, 3001000, 2001000, 25000000, 0))
+	readings, err = n.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 3)
+
+	assert.Equal(t, "eth0", readings[0].Device)
+	assert.InDelta(t, 40.0, readings[0].Value, 0.001)
+	assert.InDelta(t, 24.0, readings[0].Raw["rx_percent"], 0.001)
+	assert.InDelta(t, 16.0, readings[0].Raw["tx_percent"], 0.001)
+	assert.Equal(t, 2000000.0, readings[0].Raw["tx_bytes_per_second"])
+
+	assert.Equal(t, "eth1", readings[1].Device)
+	assert.InDelta(t, 20.0, readings[1].Value, 0.001)
+
+	// the host-wide reading is the one of the busiest interface
+	assert.Equal(t, models.MetricNet, readings[2].Metric)
+	assert.Empty(t, readings[2].Device)
+	assert.InDelta(t, 40.0, readings[2].Value, 0.001)
+}
+
+func Test_ProcNet_SampleFilter(t *testing.T) {
+	proc, sys := t.TempDir(), t.TempDir()
+	writeInterfaces(t, sys)
+
+	n := NewProcNet(proc, sys, NameFilter{Include: []string{"eth*"}, Exclude: []string{"eth0"}})
+	n.now = fakeClock(time.Second)
+
+	writeFile(t, proc, "net/dev", fmt.Sprintf(netDev, 0, 0, 0, 0, 0, 0))
+	_, err := n.Sample(context.Background())
+	require.NoError(t, err)
+
+	writeFile(t, proc, "net/dev", fmt.Sprintf(netDev, 0, 0, 1000, 1000, 1000, 1000))
+	readings, err := n.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, "eth1", readings[0].Device)
+}
+
+func Test_ProcNet_SampleNoPhysicalInterface(t *testing.T) {
+	proc, sys := t.TempDir(), t.TempDir()
+	writeFile(t, sys, "class/net/lo/speed", "10000\n")
+	writeFile(t, sys, "class/net/eth0/speed", "10000\n")
+	writeFile(t, proc, "net/dev", fmt.Sprintf(netDev, 0, 0, 0, 0, 0, 0))
+
+	n := NewProcNet(proc, sys, NameFilter{})
+	n.now = fakeClock(time.Second)
+
+	readings, err := n.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+
+	// a veth interface of a container has no device
+	readings, err = n.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.Reading{{Metric: models.MetricNet, Unit: models.UnitPercent}}, readings)
+}
+
+func Test_ProcNet_SampleNoClassNet(t *testing.T) {
+	_, err := NewProcNet(t.TempDir(), t.TempDir(), NameFilter{}).Sample(context.Background())
+	assert.Error(t, err)
+}
+
+const diskStatsLine = "   8       %d %s %d 0 %d 0 %d 0 %d 0 0 %d %d 0 0 0 0 0 0\n"
+
+// diskStatsOf formats a diskstats line of a device with reads, read sectors,
+// writes, written sectors, io_ticks and weighted io_ticks.
+func diskStatsOf(minor int, name string, counters ...int) string {
+	args := []any{minor, name}
+	for _, c := range counters {
+		args = append(args, c)
+	}
+	return fmt.Sprintf(diskStatsLine, args...)
+}
+
+func Test_ProcDisk_SampleShortInterval(t *testing.T) {
+	root := t.TempDir()
+	d := NewProcDisk(root)
+	d.now = fakeClock(500 * time.Microsecond)
+
+	writeFile(t, root, "diskstats", diskStatsOf(0, "sda", 0, 0, 0, 0, 1000, 1000))
+	_, err := d.Sample(context.Background())
+	require.NoError(t, err)
+
+	readings, err := d.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, 0.0, readings[0].Value, "an interval shorter than a millisecond is not truncated")
+	assert.Equal(t, 0.0, readings[0].Raw["queue_length"])
+	assert.Equal(t, 0.5, readings[0].Raw["elapsed_ms"])
+}
+
+func Test_ProcDisk_Sample(t *testing.T) {
+	root := t.TempDir()
+	d := NewProcDisk(root)
+	d.now = fakeClock(time.Second)
+
+	writeFile(t, root, "diskstats",
+		diskStatsOf(0, "sda", 0, 0, 0, 0, 1000, 0)+
+			diskStatsOf(1, "sda1", 0, 0, 0, 0, 1000, 0)+
+			diskStatsOf(0, "nvme0n1", 0, 0, 0, 0, 0, 0)+
+			diskStatsOf(0, "loop0", 0, 0, 0, 0, 0, 0))
+	readings, err := d.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+
+	// sda busy for 300 ms with 100 reads of 4 KiB and an average queue of 1.5,
+	// nvme0n1 busy for 200 ms with 50 writes of 1 MiB
+	writeFile(t, root, "diskstats",
+		diskStatsOf(0, "sda", 100, 800, 0, 0, 1300, 1500)+
+			diskStatsOf(1, "sda1", 100, 800, 0, 0, 1300, 1500)+
+			diskStatsOf(0, "nvme0n1", 0, 0, 50, 102400, 200, 200)+
+			diskStatsOf(0, "loop0", 0, 0, 0, 0, 900, 0))
+	readings, err = d.Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 4)
+
+	nvme := readings[0]
+	assert.Equal(t, "nvme0n1", nvme.Device)
+	assert.InDelta(t, 20.0, nvme.Value, 0.001)
+	assert.Equal(t, 50.0, nvme.Raw["writes_per_second"])
+	assert.Equal(t, 52428800.0, nvme.Raw["write_bytes_per_second"])
+
+	sda := readings[1]
+	assert.Equal(t, "sda", sda.Device)
+	assert.InDelta(t, 30.0, sda.Value, 0.001)
+	assert.Equal(t, 100.0, sda.Raw["reads_per_second"])
+	assert.Equal(t, 409600.0, sda.Raw["read_bytes_per_second"])
+	assert.InDelta(t, 1.5, sda.Raw["queue_length"], 0.001)
+
+	assert.Equal(t, "sda1", readings[2].Device)
+
+	// the host-wide reading is the one of the busiest disk
+	assert.Equal(t, models.MetricDisk, readings[3].Metric)
+	assert.Empty(t, readings[3].Device)
+	assert.InDelta(t, 30.0, readings[3].Value, 0.001)
+}
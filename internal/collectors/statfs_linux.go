@@ -0,0 +1,64 @@
+//go:build linux
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"health-checker/internal/models"
+	"log/slog"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// StatfsDiskSpace reports free space of every mounted block device in
+// percent, followed by the free space of the fullest one. The volumes are
+// named by their mount point, which is looked up under hostRoot, so the
+// host is monitored from a container with its root filesystem mounted there.
+type StatfsDiskSpace struct {
+	procRoot string
+	hostRoot string
+	interval time.Duration
+}
+
+func NewStatfsDiskSpace(procRoot, hostRoot string, interval time.Duration) *StatfsDiskSpace {
+	return &StatfsDiskSpace{procRoot: procRoot, hostRoot: hostRoot, interval: interval}
+}
+
+func (d *StatfsDiskSpace) Name() string {
+	return models.MetricDiskFree
+}
+
+func (d *StatfsDiskSpace) Interval() time.Duration {
+	return d.interval
+}
+
+func (d *StatfsDiskSpace) Sample(_ context.Context) ([]models.Reading, error) {
+	mounts, err := readMounts(filepath.Join(d.procRoot, "mounts"))
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []models.Reading
+	for _, mount := range mounts {
+		var st syscall.Statfs_t
+		if err = syscall.Statfs(filepath.Join(d.hostRoot, mount), &st); err != nil {
+			slog.Debug("volume is skipped", "volume", mount, "error", err)
+			continue
+		}
+		if st.Blocks == 0 {
+			continue
+		}
+
+		// the space reserved for root is not available to the applications
+		free := float64(st.Bavail) * float64(st.Bsize)
+		size := float64(st.Blocks) * float64(st.Bsize)
+		readings = append(readings, volumeReading(mount, free, size))
+	}
+
+	if len(readings) == 0 {
+		return nil, errors.New("no disk data")
+	}
+	return appendFullest(readings), nil
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package collectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StatfsDiskSpace_HostRoot(t *testing.T) {
+	proc, host := t.TempDir(), t.TempDir()
+	writeFile(t, proc, "mounts", "/dev/sda1 / ext4 rw 0 0\n/dev/sdb1 /data ext4 rw 0 0\n")
+
+	readings, err := NewStatfsDiskSpace(proc, host, time.Minute).Sample(context.Background())
+	require.NoError(t, err)
+	require.Len(t, readings, 2, "the mount points missing under the host root are skipped")
+	assert.Equal(t, "/", readings[0].Device)
+	assert.Positive(t, readings[0].Raw["size_bytes"])
+}
@@ -0,0 +1,364 @@
+//go:build windows
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+type proc struct {
+	Name                 string
+	PercentProcessorTime uint64
+	TimeStamp_Sys100NS   uint64
+}
+
+type mem struct {
+	AvailableBytes uint64
+	CommittedBytes uint64
+	CommitLimit    uint64
+}
+
+type computerSystem struct {
+	TotalPhysicalMemory uint64
+}
+
+type pageFile struct {
+	AllocatedBaseSize uint32
+	CurrentUsage      uint32
+}
+
+type net struct {
+	Name                string
+	CurrentBandwidth    uint64
+	BytesReceivedPersec uint64
+	BytesSentPersec     uint64
+}
+
+type disk struct {
+	Name                 string
+	PercentIdleTime      uint64
+	DiskReadBytesPersec  uint64
+	DiskWriteBytesPersec uint64
+	DiskReadsPersec      uint32
+	DiskWritesPersec     uint32
+	AvgDiskQueueLength   uint64
+}
+
+type networkName struct {
+	Name                 string
+	InterfaceDescription string
+}
+
+type diskFreeSpace struct {
+	FreeSpace uint64
+	Size      uint64
+	Name      string
+}
+
+// Default returns the WMI collectors.
+func Default(cfg configs.Checker) []Collector {
+	return []Collector{
+		&CPU{},
+		&RAM{},
+		&Net{filter: NameFilter{Include: cfg.NetInclude, Exclude: cfg.NetExclude}},
+		&Disk{},
+		&DiskSpace{interval: cfg.DiskFreeInterval},
+	}
+}
+
+// CPU reports processor utilization in percent, for every core and for
+// the whole processor. The utilization is computed against the raw counters
+// of the previous sample, so the first sample only stores the counters and
+// produces no readings.
+type CPU struct {
+	prev map[string]proc
+}
+
+func (c *CPU) Name() string {
+	return models.MetricCPU
+}
+
+func (c *CPU) Sample(_ context.Context) ([]models.Reading, error) {
+	var point []proc
+
+	const query = "SELECT Name, PercentProcessorTime, TimeStamp_Sys100NS FROM Win32_PerfRawData_PerfOS_Processor"
+
+	err := wmi.Query(query, &point)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := make(map[string]proc, len(point))
+	var cores []string
+	for _, p := range point {
+		name := p.Name
+		if name == "_Total" {
+			name = ""
+		} else {
+			cores = append(cores, name)
+		}
+		cur[name] = p
+	}
+	if _, ok := cur[""]; !ok {
+		return nil, errors.New("no processor data")
+	}
+	sort.Slice(cores, func(i, j int) bool {
+		return models.LessDevice(cores[i], cores[j])
+	})
+
+	prev := c.prev
+	c.prev = cur
+
+	// the cores are reported before the whole processor, so that the zone
+	// of the processor accounts for them
+	var readings []models.Reading
+	for _, name := range append(cores, "") {
+		p, ok := prev[name]
+		t := cur[name]
+		if !ok || p.TimeStamp_Sys100NS == 0 || t.TimeStamp_Sys100NS <= p.TimeStamp_Sys100NS {
+			continue
+		}
+
+		/*
+			CPU utilization calculation mechanism
+			is based on https://learn.microsoft.com/en-us/windows/win32/wmisdk/monitoring-performance-data#using-raw-performance-data-classes
+		*/
+		idle := float64(t.PercentProcessorTime - p.PercentProcessorTime)
+		total := float64(t.TimeStamp_Sys100NS - p.TimeStamp_Sys100NS)
+
+		readings = append(readings, models.Reading{
+			Metric: models.MetricCPU,
+			Device: name,
+			Value:  (1.0 - idle/total) * 100,
+			Unit:   models.UnitPercent,
+			Raw: map[string]float64{
+				"idle_100ns":  idle,
+				"total_100ns": total,
+			},
+		})
+	}
+
+	return readings, nil
+}
+
+// RAM reports used memory in percent of the memory visible to the system.
+type RAM struct {
+	total uint64
+}
+
+func (r *RAM) Name() string {
+	return models.MetricRAM
+}
+
+func (r *RAM) Sample(_ context.Context) ([]models.Reading, error) {
+	if r.total == 0 {
+		var system []computerSystem
+
+		err := wmi.Query("SELECT TotalPhysicalMemory FROM Win32_ComputerSystem", &system)
+		if err != nil {
+			return nil, err
+		}
+		if len(system) == 0 || system[0].TotalPhysicalMemory == 0 {
+			return nil, errors.New("no memory data")
+		}
+
+		r.total = system[0].TotalPhysicalMemory
+		slog.Debug("", "memory total", r.total)
+	}
+
+	var memoryPoint []mem
+
+	err := wmi.Query("SELECT AvailableBytes, CommittedBytes, CommitLimit FROM Win32_PerfFormattedData_PerfOS_Memory", &memoryPoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(memoryPoint) == 0 {
+		return nil, errors.New("no memory data")
+	}
+
+	var pageFiles []pageFile
+
+	err = wmi.Query("SELECT AllocatedBaseSize, CurrentUsage FROM Win32_PageFileUsage", &pageFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// the page file usage is reported in megabytes
+	var swapTotal, swapUsed uint64
+	for _, p := range pageFiles {
+		swapTotal += uint64(p.AllocatedBaseSize) * 1024 * 1024
+		swapUsed += uint64(p.CurrentUsage) * 1024 * 1024
+	}
+
+	return []models.Reading{memReading(memory{
+		total:       float64(r.total),
+		available:   float64(memoryPoint[0].AvailableBytes),
+		swapTotal:   float64(swapTotal),
+		swapFree:    float64(swapTotal - swapUsed),
+		commit:      float64(memoryPoint[0].CommittedBytes),
+		commitLimit: float64(memoryPoint[0].CommitLimit),
+	})}, nil
+}
+
+// Net reports utilization of every connected network adapter selected by
+// the filter in percent, followed by the utilization of the busiest one.
+// Without any such adapter the utilization is reported as zero.
+type Net struct {
+	filter NameFilter
+	// warned is set once the missing adapters are logged.
+	warned bool
+}
+
+func (n *Net) Name() string {
+	return models.MetricNet
+}
+
+func (n *Net) Sample(_ context.Context) ([]models.Reading, error) {
+	var adapters []networkName
+
+	err := wmi.QueryNamespace("SELECT Name, InterfaceDescription FROM MSFT_NetAdapter WHERE ConnectorPresent=1", &adapters, `root\StandardCimv2`)
+	if err != nil {
+		return nil, err
+	}
+
+	var netInfo []net
+
+	err = wmi.Query("SELECT Name, CurrentBandwidth, BytesReceivedPersec, BytesSentPersec FROM Win32_PerfFormattedData_Tcpip_NetworkInterface", &netInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	perf := make(map[string]net, len(netInfo))
+	for _, v := range netInfo {
+		perf[v.Name] = v
+	}
+
+	var readings []models.Reading
+	for _, a := range adapters {
+		if !n.filter.Match(a.Name) {
+			continue
+		}
+
+		v, ok := perf[perfInstanceName(a.InterfaceDescription)]
+		if !ok || v.CurrentBandwidth == 0 {
+			slog.Debug("network adapter is skipped", "adapter", a.Name)
+			continue
+		}
+
+		readings = append(readings, netReading(a.Name, float64(v.BytesReceivedPersec), float64(v.BytesSentPersec), float64(v.CurrentBandwidth)))
+	}
+
+	if len(readings) == 0 && !n.warned {
+		slog.Warn("no connected network adapter is selected, net utilization is reported as 0")
+	}
+	n.warned = len(readings) == 0
+	if len(readings) == 0 {
+		return []models.Reading{idleNetReading()}, nil
+	}
+	return appendBusiest(readings), nil
+}
+
+// perfInstanceName returns the name of the performance counter instance of
+// an adapter, where the characters reserved by the counter paths are replaced.
+func perfInstanceName(description string) string {
+	return strings.NewReplacer("(", "[", ")", "]", "#", "_", "/", "_", "\\", "_").Replace(description)
+}
+
+// Disk reports the busy time of every physical disk and volume in percent,
+// followed by the busy time of the busiest one. The readings also carry the
+// throughput, IOPS and queue length.
+type Disk struct{}
+
+func (d *Disk) Name() string {
+	return models.MetricDisk
+}
+
+func (d *Disk) Sample(_ context.Context) ([]models.Reading, error) {
+	var physical, logical []disk
+
+	const fields = "Name, PercentIdleTime, DiskReadBytesPersec, DiskWriteBytesPersec, DiskReadsPersec, DiskWritesPersec, AvgDiskQueueLength"
+
+	err := wmi.Query("SELECT "+fields+" FROM Win32_PerfFormattedData_PerfDisk_PhysicalDisk", &physical)
+	if err != nil {
+		return nil, err
+	}
+	err = wmi.Query("SELECT "+fields+" FROM Win32_PerfFormattedData_PerfDisk_LogicalDisk", &logical)
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []models.Reading
+	for _, v := range append(physical, logical...) {
+		// the _Total instance sums the busy time of every disk
+		if v.Name == "_Total" {
+			continue
+		}
+
+		readings = append(readings, models.Reading{
+			Metric: models.MetricDisk,
+			Device: v.Name,
+			Value:  100 - float64(min(v.PercentIdleTime, 100)),
+			Unit:   models.UnitPercent,
+			Raw: map[string]float64{
+				"read_bytes_per_second":  float64(v.DiskReadBytesPersec),
+				"write_bytes_per_second": float64(v.DiskWriteBytesPersec),
+				"reads_per_second":       float64(v.DiskReadsPersec),
+				"writes_per_second":      float64(v.DiskWritesPersec),
+				"queue_length":           float64(v.AvgDiskQueueLength),
+			},
+		})
+	}
+
+	if len(readings) == 0 {
+		return nil, errors.New("no disk data")
+	}
+	return appendBusiest(readings), nil
+}
+
+// DiskSpace reports free space of every local logical disk in percent,
+// followed by the free space of the fullest one.
+type DiskSpace struct {
+	interval time.Duration
+}
+
+func (d *DiskSpace) Name() string {
+	return models.MetricDiskFree
+}
+
+func (d *DiskSpace) Interval() time.Duration {
+	return d.interval
+}
+
+func (d *DiskSpace) Sample(_ context.Context) ([]models.Reading, error) {
+	var diskInfo []diskFreeSpace
+
+	// drive type 3 is a local disk, removable and network drives are skipped
+	err := wmi.Query("SELECT FreeSpace, Size, Name FROM Win32_LogicalDisk WHERE DriveType = 3", &diskInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	readings := make([]models.Reading, 0, len(diskInfo))
+	for _, v := range diskInfo {
+		if v.Size == 0 {
+			continue
+		}
+		slog.Debug("", "disk free space", v.FreeSpace, "disk size", v.Size, "disk name", v.Name)
+
+		readings = append(readings, volumeReading(v.Name, float64(v.FreeSpace), float64(v.Size)))
+	}
+
+	if len(readings) == 0 {
+		return nil, errors.New("no disk data")
+	}
+	return appendFullest(readings), nil
+}
@@ -1,34 +1,512 @@
 package configs
 
 import (
+	"errors"
 	"flag"
-	"log/slog"
-
+	"fmt"
+	"health-checker/internal/models"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// Threshold holds the zone boundaries of a metric.
+type Threshold struct {
+	Warning float64 `env:"WARNING" yaml:"warning"`
+	Danger  float64 `env:"DANGER" yaml:"danger"`
+	// Streak is the number of intervals a metric has to stay above the
+	// warning threshold before it is reported in the warning zone.
+	Streak int `env:"STREAK" yaml:"streak"`
+	// Decay is how the streak is lost while a metric is below the warning
+	// threshold, one of step or reset.
+	Decay string `env:"DECAY" yaml:"decay"`
+	// WarningExit and DangerExit are the thresholds a metric has to drop
+	// below to leave the zone, the entry thresholds when zero.
+	WarningExit float64 `env:"WARNING_EXIT" yaml:"warning_exit"`
+	DangerExit  float64 `env:"DANGER_EXIT" yaml:"danger_exit"`
+	// WarningDwell and DangerDwell are the minimum time a metric stays in
+	// the zone once it has entered it.
+	WarningDwell time.Duration `env:"WARNING_DWELL" yaml:"warning_dwell"`
+	DangerDwell  time.Duration `env:"DANGER_DWELL" yaml:"danger_dwell"`
+	// Smoothing is applied to the samples before the zone is evaluated.
+	Smoothing Smoothing `envPrefix:"SMOOTHING_" yaml:"smoothing"`
+}
+
+// metrics are the names of the metrics that can be routed to a webhook.
+var metrics = []string{models.MetricCPU, models.MetricRAM, models.MetricNet, models.MetricDisk, models.MetricDiskFree}
+
+// Decay policies of the streak.
+const (
+	// DecayStep loses one interval of the streak for every interval below
+	// the warning threshold.
+	DecayStep = "step"
+	// DecayReset loses the whole streak once a metric drops below the
+	// warning threshold.
+	DecayReset = "reset"
+)
+
+// Smoothing methods.
+const (
+	SmoothingSMA        = "sma"
+	SmoothingEMA        = "ema"
+	SmoothingMedian     = "median"
+	SmoothingPercentile = "percentile"
 )
 
+// Smoothing selects how the samples of a metric are smoothed.
+type Smoothing struct {
+	// Method is one of sma, ema, median or percentile.
+	Method string `env:"METHOD" yaml:"method"`
+	// Window is the number of samples sma, median and percentile are
+	// computed over.
+	Window int `env:"WINDOW" yaml:"window"`
+	// Alpha is the weight of the newest sample of ema in (0, 1].
+	Alpha float64 `env:"ALPHA" yaml:"alpha"`
+	// Percentile is the percentile in [0, 100] computed by percentile.
+	Percentile float64 `env:"PERCENTILE" yaml:"percentile"`
+}
+
+// smoothing returns the default smoothing over window samples.
+func smoothing(window int) Smoothing {
+	return Smoothing{Method: SmoothingSMA, Window: window, Alpha: 0.5, Percentile: 95}
+}
+
+// FreeSpace holds the free space zone boundaries of a volume. A volume is in
+// a zone when either its free space in percent or in bytes drops to the
+// boundary, zero disables a boundary.
+type FreeSpace struct {
+	WarningPercent float64 `env:"WARNING_PERCENT" yaml:"warning_percent"`
+	DangerPercent  float64 `env:"DANGER_PERCENT" yaml:"danger_percent"`
+	WarningBytes   uint64  `env:"WARNING_BYTES" yaml:"warning_bytes"`
+	DangerBytes    uint64  `env:"DANGER_BYTES" yaml:"danger_bytes"`
+}
+
+// Webhook is a receiver of the zone transitions of the metrics.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Metrics are the metrics routed to the webhook, every metric when empty.
+	Metrics []string `yaml:"metrics"`
+}
+
 type Checker struct {
-	Interval  time.Duration `env:"CHECK_INTERVAL"`
-	Address   string        `env:"ADDRESS"`
-	Port      string        `env:"PORT"`
-	DebugMode bool          `env:"DEBUG_MODE"`
+	ConfigFile string        `env:"CONFIG_FILE" yaml:"-"`
+	Interval   time.Duration `env:"CHECK_INTERVAL" yaml:"interval"`
+	Address    string        `env:"ADDRESS" yaml:"address"`
+	Port       string        `env:"PORT" yaml:"port"`
+	DebugMode  bool          `env:"DEBUG_MODE" yaml:"debug"`
+	ProcRoot   string        `env:"PROC_ROOT" yaml:"proc_root"`
+	SysRoot    string        `env:"SYS_ROOT" yaml:"sys_root"`
+	// HostRoot is the mount point of the root filesystem of the host the
+	// free space of the mount points listed in ProcRoot is read under.
+	HostRoot string `env:"HOST_ROOT" yaml:"host_root"`
+	// ReadyIntervals is the number of intervals a collector may go without
+	// a sample before /readyz reports the service as not ready.
+	ReadyIntervals int `env:"READY_INTERVALS" yaml:"ready_intervals"`
+	// MaxAge is the age after which a sample is reported as stale,
+	// ReadyIntervals intervals of its collector when zero. A collector polled
+	// on its own interval gets at least ReadyIntervals of its intervals.
+	MaxAge time.Duration `env:"MAX_AGE" yaml:"max_age"`
+	// StaleUnavailable makes /check respond with 503 while a metric is stale.
+	StaleUnavailable bool `env:"STALE_UNAVAILABLE" yaml:"stale_unavailable"`
+	// RetryMaxBackoff caps the delay between retries of a failing collector.
+	RetryMaxBackoff time.Duration `env:"RETRY_MAX_BACKOFF" yaml:"retry_max_backoff"`
+	// HistorySize is the number of zone transitions kept for /history.
+	HistorySize int `env:"HISTORY_SIZE" yaml:"history_size"`
+	// StorageDir is the directory the samples of the metrics are stored
+	// in, they are kept in memory and lost on restart when empty.
+	StorageDir string `env:"STORAGE_DIR" yaml:"storage_dir"`
+	// StorageRawSamples is the number of raw samples stored per metric.
+	StorageRawSamples int `env:"STORAGE_RAW_SAMPLES" yaml:"storage_raw_samples"`
+
+	// CPUCoreWarning reports the CPU in the warning zone when a single core
+	// stays above it for the CPU streak, disabled when zero.
+	CPUCoreWarning float64 `env:"CPU_CORE_WARNING" yaml:"cpu_core_warning"`
+
+	// NetInclude and NetExclude select the monitored network interfaces by
+	// name with shell patterns, every physical interface is monitored when
+	// both are empty.
+	NetInclude []string `env:"NET_INCLUDE" yaml:"net_include"`
+	NetExclude []string `env:"NET_EXCLUDE" yaml:"net_exclude"`
+
+	CPU  Threshold `envPrefix:"CPU_" yaml:"cpu"`
+	RAM  Threshold `envPrefix:"RAM_" yaml:"ram"`
+	Net  Threshold `envPrefix:"NET_" yaml:"net"`
+	Disk Threshold `envPrefix:"DISK_" yaml:"disk"`
+
+	// DiskFree are the free space boundaries of every volume, DiskFreeVolumes
+	// overrides them for the volumes it lists.
+	DiskFree        FreeSpace            `envPrefix:"DISK_FREE_" yaml:"disk_free"`
+	DiskFreeVolumes map[string]FreeSpace `yaml:"disk_free_volumes"`
+	// DiskFreeInterval is the interval the free space is refreshed at.
+	DiskFreeInterval time.Duration `env:"DISK_FREE_INTERVAL" yaml:"disk_free_interval"`
+
+	// Webhooks receive the zone transitions of the metrics routed to them,
+	// WebhookURLs receive the transitions of every metric.
+	Webhooks    []Webhook `yaml:"webhooks"`
+	WebhookURLs []string  `env:"WEBHOOK_URLS" yaml:"webhook_urls"`
+	// WebhookRetries is the number of retries of a failed delivery, the
+	// first one after WebhookBackoff and every next one twice as late, at
+	// most after RetryMaxBackoff.
+	WebhookRetries int           `env:"WEBHOOK_RETRIES" yaml:"webhook_retries"`
+	WebhookBackoff time.Duration `env:"WEBHOOK_BACKOFF" yaml:"webhook_backoff"`
+	WebhookTimeout time.Duration `env:"WEBHOOK_TIMEOUT" yaml:"webhook_timeout"`
+	// WebhookRenotify is the interval a metric staying in the danger zone
+	// is notified again at, disabled when zero.
+	WebhookRenotify time.Duration `env:"WEBHOOK_RENOTIFY" yaml:"webhook_renotify"`
+
+	// AlertmanagerURLs are the base urls of the Alertmanagers the metrics
+	// in the warning and danger zones are pushed to as alerts, delivered
+	// like the webhooks.
+	AlertmanagerURLs []string `env:"ALERTMANAGER_URLS" yaml:"alertmanager_urls"`
+	// AlertmanagerResend is the interval the firing alerts are pushed again
+	// at, it has to be shorter than the resolve timeout of Alertmanager.
+	AlertmanagerResend time.Duration `env:"ALERTMANAGER_RESEND" yaml:"alertmanager_resend"`
 }
 
-var checker Checker
+// FreeSpace returns the free space boundaries of volume.
+func (c Checker) FreeSpace(volume string) FreeSpace {
+	if t, ok := c.DiskFreeVolumes[volume]; ok {
+		return t
+	}
+	return c.DiskFree
+}
 
-func GetCheckerCfg() Checker {
-	flag.DurationVar(&checker.Interval, "i", 60*time.Second, "check interval")
-	flag.StringVar(&checker.Address, "a", "localhost", "address")
-	flag.StringVar(&checker.Port, "p", "8080", "port")
-	flag.BoolVar(&checker.DebugMode, "d", false, "debug mode")
-	flag.Parse()
+// DefaultChecker returns the configuration used when nothing is specified.
+func DefaultChecker() Checker {
+	return Checker{
+		Interval:        60 * time.Second,
+		Address:         "localhost",
+		Port:            "8080",
+		ProcRoot:        "/proc",
+		SysRoot:         "/sys",
+		HostRoot:        "/",
+		ReadyIntervals:  3,
+		RetryMaxBackoff: 10 * time.Minute,
+		HistorySize:     100,
+		CPU:             Threshold{Warning: 75, Danger: 90, Streak: 10, Decay: DecayStep, Smoothing: smoothing(1)},
+		RAM:             Threshold{Warning: 75, Danger: 90, Streak: 10, Decay: DecayStep, Smoothing: smoothing(5)},
+		Net:             Threshold{Warning: 80, Danger: 90, Streak: 10, Decay: DecayStep, Smoothing: smoothing(5)},
+		Disk:            Threshold{Warning: 80, Danger: 90, Streak: 10, Decay: DecayStep, Smoothing: smoothing(5)},
 
-	err := env.Parse(&checker)
+		DiskFree:         FreeSpace{WarningPercent: 15, DangerPercent: 5},
+		DiskFreeInterval: 5 * time.Minute,
+
+		WebhookRetries:  3,
+		WebhookBackoff:  time.Second,
+		WebhookTimeout:  10 * time.Second,
+		WebhookRenotify: time.Hour,
+
+		AlertmanagerResend: time.Minute,
+
+		StorageRawSamples: 10080,
+	}
+}
+
+// GetCheckerCfg loads the configuration from the command line arguments,
+// the configuration file and the environment.
+func GetCheckerCfg() (Checker, error) {
+	return Load(os.Args[1:])
+}
+
+// Load builds the configuration with the following precedence, from lowest
+// to highest: defaults, configuration file, flags, environment variables.
+// The returned error lists every invalid field.
+func Load(args []string) (Checker, error) {
+	checker := DefaultChecker()
+
+	fs := flag.NewFlagSet("health-checker", flag.ContinueOnError)
+	fs.StringVar(&checker.ConfigFile, "c", "", "configuration file (yaml or json)")
+	fs.DurationVar(&checker.Interval, "i", checker.Interval, "check interval")
+	fs.StringVar(&checker.Address, "a", checker.Address, "address")
+	fs.StringVar(&checker.Port, "p", checker.Port, "port")
+	fs.BoolVar(&checker.DebugMode, "d", checker.DebugMode, "debug mode")
+	fs.StringVar(&checker.ProcRoot, "proc", checker.ProcRoot, "procfs mount point (linux only)")
+	fs.StringVar(&checker.SysRoot, "sys", checker.SysRoot, "sysfs mount point (linux only)")
+	fs.StringVar(&checker.HostRoot, "host-root", checker.HostRoot, "host root filesystem mount point for the free space (linux only)")
+	fs.IntVar(&checker.ReadyIntervals, "ready-intervals", checker.ReadyIntervals, "intervals without a sample before not ready")
+	fs.DurationVar(&checker.MaxAge, "max-age", checker.MaxAge, "age after which a sample is stale (default ready-intervals intervals)")
+	fs.BoolVar(&checker.StaleUnavailable, "stale-503", checker.StaleUnavailable, "respond with 503 while a metric is stale")
+	fs.DurationVar(&checker.RetryMaxBackoff, "retry-max-backoff", checker.RetryMaxBackoff, "max delay between retries of a failing collector")
+	fs.IntVar(&checker.HistorySize, "history-size", checker.HistorySize, "number of zone transitions kept")
+	fs.StringVar(&checker.StorageDir, "storage-dir", checker.StorageDir, "directory the samples are stored in (default in memory)")
+	fs.IntVar(&checker.StorageRawSamples, "storage-raw-samples", checker.StorageRawSamples, "number of raw samples stored per metric")
+	fs.Float64Var(&checker.CPUCoreWarning, "cpu-core-warning", checker.CPUCoreWarning, "warning threshold of a single cpu core (0 disables)")
+	fs.Var((*stringList)(&checker.NetInclude), "net-include", "comma separated patterns of the monitored network interfaces")
+	fs.Var((*stringList)(&checker.NetExclude), "net-exclude", "comma separated patterns of the ignored network interfaces")
+	thresholdVar(fs, &checker.CPU, "cpu")
+	thresholdVar(fs, &checker.RAM, "ram")
+	thresholdVar(fs, &checker.Net, "net")
+	thresholdVar(fs, &checker.Disk, "disk")
+	fs.Float64Var(&checker.DiskFree.WarningPercent, "disk-free-warning", checker.DiskFree.WarningPercent, "free space warning zone threshold in percent")
+	fs.Float64Var(&checker.DiskFree.DangerPercent, "disk-free-danger", checker.DiskFree.DangerPercent, "free space danger zone threshold in percent")
+	fs.Uint64Var(&checker.DiskFree.WarningBytes, "disk-free-warning-bytes", checker.DiskFree.WarningBytes, "free space warning zone threshold in bytes")
+	fs.Uint64Var(&checker.DiskFree.DangerBytes, "disk-free-danger-bytes", checker.DiskFree.DangerBytes, "free space danger zone threshold in bytes")
+	fs.DurationVar(&checker.DiskFreeInterval, "disk-free-interval", checker.DiskFreeInterval, "free space refresh interval")
+	fs.Var((*stringList)(&checker.WebhookURLs), "webhook-urls", "comma separated urls notified of the zone transitions of every metric")
+	fs.IntVar(&checker.WebhookRetries, "webhook-retries", checker.WebhookRetries, "retries of a failed webhook delivery")
+	fs.DurationVar(&checker.WebhookBackoff, "webhook-backoff", checker.WebhookBackoff, "delay before the first retry of a webhook delivery")
+	fs.DurationVar(&checker.WebhookTimeout, "webhook-timeout", checker.WebhookTimeout, "timeout of a webhook request")
+	fs.Var((*stringList)(&checker.AlertmanagerURLs), "alertmanager-urls", "comma separated base urls of the alertmanagers")
+	fs.DurationVar(&checker.AlertmanagerResend, "alertmanager-resend", checker.AlertmanagerResend, "interval the firing alerts are pushed again at")
+	fs.DurationVar(&checker.WebhookRenotify, "webhook-renotify", checker.WebhookRenotify, "interval a metric in the danger zone is notified again at (0 disables)")
+
+	if err := fs.Parse(args); err != nil {
+		return checker, err
+	}
+
+	// the file is loaded over the parsed flags, so the explicitly set
+	// flags are remembered and applied again afterwards
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	var errs []error
+
+	if path, ok := os.LookupEnv("CONFIG_FILE"); ok {
+		checker.ConfigFile = path
+	}
+	if checker.ConfigFile != "" {
+		errs = append(errs, loadFile(checker.ConfigFile, &checker))
+	}
+
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := env.Parse(&checker); err != nil {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, checker.Validate())
+
+	return checker, errors.Join(errs...)
+}
+
+func thresholdVar(fs *flag.FlagSet, t *Threshold, name string) {
+	fs.Float64Var(&t.Warning, name+"-warning", t.Warning, name+" warning zone threshold")
+	fs.Float64Var(&t.Danger, name+"-danger", t.Danger, name+" danger zone threshold")
+	fs.IntVar(&t.Streak, name+"-streak", t.Streak, name+" intervals above warning threshold before warning zone")
+	fs.StringVar(&t.Decay, name+"-decay", t.Decay, name+" streak decay below warning threshold: step or reset")
+	fs.Float64Var(&t.WarningExit, name+"-warning-exit", t.WarningExit, name+" threshold to leave the warning zone (default warning threshold)")
+	fs.Float64Var(&t.DangerExit, name+"-danger-exit", t.DangerExit, name+" threshold to leave the danger zone (default danger threshold)")
+	fs.DurationVar(&t.WarningDwell, name+"-warning-dwell", t.WarningDwell, name+" minimum time in the warning zone")
+	fs.DurationVar(&t.DangerDwell, name+"-danger-dwell", t.DangerDwell, name+" minimum time in the danger zone")
+	fs.StringVar(&t.Smoothing.Method, name+"-smoothing", t.Smoothing.Method, name+" smoothing method: sma, ema, median or percentile")
+	fs.IntVar(&t.Smoothing.Window, name+"-smoothing-window", t.Smoothing.Window, name+" samples the smoothing is computed over")
+	fs.Float64Var(&t.Smoothing.Alpha, name+"-smoothing-alpha", t.Smoothing.Alpha, name+" weight of the newest sample of ema")
+	fs.Float64Var(&t.Smoothing.Percentile, name+"-smoothing-percentile", t.Smoothing.Percentile, name+" percentile of the percentile smoothing")
+}
+
+// stringList is a flag holding a comma separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// loadFile decodes a yaml or json file into checker. Since json is a subset
+// of yaml both formats are handled by the yaml decoder.
+func loadFile(path string, checker *Checker) error {
+	f, err := os.Open(path)
 	if err != nil {
-		slog.Error("ошибка парсинга конфига: %v", err)
-		panic(err)
+		return fmt.Errorf("config file: %w", err)
 	}
-	return checker
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err = dec.Decode(checker); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate returns an error describing every invalid field of the configuration.
+func (c Checker) Validate() error {
+	var errs []error
+
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("incorrect interval, please specify > 0"))
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max age must be >= 0, got %s", c.MaxAge))
+	}
+	if c.RetryMaxBackoff < 0 {
+		errs = append(errs, fmt.Errorf("retry max backoff must be >= 0, got %s", c.RetryMaxBackoff))
+	}
+	if c.ReadyIntervals < 1 {
+		errs = append(errs, fmt.Errorf("ready intervals must be >= 1, got %d", c.ReadyIntervals))
+	}
+	if c.HistorySize < 1 {
+		errs = append(errs, fmt.Errorf("history size must be >= 1, got %d", c.HistorySize))
+	}
+	if c.StorageRawSamples < 1 {
+		errs = append(errs, fmt.Errorf("storage raw samples must be >= 1, got %d", c.StorageRawSamples))
+	}
+
+	if c.CPUCoreWarning < 0 {
+		errs = append(errs, fmt.Errorf("cpu core warning threshold must be >= 0, got %v", c.CPUCoreWarning))
+	}
+
+	errs = append(errs, validatePatterns("net include", c.NetInclude))
+	errs = append(errs, validatePatterns("net exclude", c.NetExclude))
+
+	errs = append(errs, c.CPU.validate("cpu"))
+	errs = append(errs, c.RAM.validate("ram"))
+	errs = append(errs, c.Net.validate("net"))
+	errs = append(errs, c.Disk.validate("disk"))
+
+	if c.DiskFreeInterval <= 0 {
+		errs = append(errs, fmt.Errorf("disk free interval must be > 0, got %s", c.DiskFreeInterval))
+	}
+	errs = append(errs, c.DiskFree.validate("disk free"))
+	for volume, t := range c.DiskFreeVolumes {
+		errs = append(errs, t.validate("disk free "+volume))
+	}
+
+	for _, u := range c.WebhookURLs {
+		errs = append(errs, Webhook{URL: u}.validate())
+	}
+	for _, w := range c.Webhooks {
+		errs = append(errs, w.validate())
+	}
+	if c.WebhookRetries < 0 {
+		errs = append(errs, fmt.Errorf("webhook retries must be >= 0, got %d", c.WebhookRetries))
+	}
+	if c.WebhookBackoff < 0 || c.WebhookTimeout < 0 || c.WebhookRenotify < 0 {
+		errs = append(errs, errors.New("webhook backoff, timeout and renotify interval must be >= 0"))
+	}
+	for _, u := range c.AlertmanagerURLs {
+		errs = append(errs, validateURL("alertmanager", u))
+	}
+	if c.AlertmanagerResend <= 0 {
+		errs = append(errs, fmt.Errorf("alertmanager resend interval must be > 0, got %s", c.AlertmanagerResend))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w Webhook) validate() error {
+	errs := []error{validateURL("webhook", w.URL)}
+
+	for _, m := range w.Metrics {
+		if !slices.Contains(metrics, m) {
+			errs = append(errs, fmt.Errorf("webhook %q: unknown metric %q", w.URL, m))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateURL checks that raw is an absolute http or https url.
+func validateURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s %q: an absolute http or https url is expected", name, raw)
+	}
+	return nil
+}
+
+func validatePatterns(name string, patterns []string) error {
+	var errs []error
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: pattern %q: %w", name, p, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t FreeSpace) validate(name string) error {
+	var errs []error
+
+	if t.WarningPercent < 0 || t.WarningPercent > 100 || t.DangerPercent < 0 || t.DangerPercent > 100 {
+		errs = append(errs, fmt.Errorf("%s: thresholds in percent must be within [0, 100]", name))
+	}
+	// free space is worse when lower, so the danger threshold is the lower one
+	if t.DangerPercent > 0 && t.WarningPercent > 0 && t.WarningPercent <= t.DangerPercent {
+		errs = append(errs, fmt.Errorf("%s: warning threshold %v%% must be greater than danger threshold %v%%", name, t.WarningPercent, t.DangerPercent))
+	}
+	if t.DangerBytes > 0 && t.WarningBytes > 0 && t.WarningBytes <= t.DangerBytes {
+		errs = append(errs, fmt.Errorf("%s: warning threshold %d bytes must be greater than danger threshold %d bytes", name, t.WarningBytes, t.DangerBytes))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s Smoothing) validate(name string) error {
+	switch s.Method {
+	case SmoothingSMA, SmoothingMedian:
+	case SmoothingEMA:
+		if s.Alpha <= 0 || s.Alpha > 1 {
+			return fmt.Errorf("%s: smoothing alpha must be within (0, 1], got %v", name, s.Alpha)
+		}
+		return nil
+	case SmoothingPercentile:
+		if s.Percentile < 0 || s.Percentile > 100 {
+			return fmt.Errorf("%s: smoothing percentile must be within [0, 100], got %v", name, s.Percentile)
+		}
+	default:
+		return fmt.Errorf("%s: unknown smoothing method %q", name, s.Method)
+	}
+
+	if s.Window < 1 {
+		return fmt.Errorf("%s: smoothing window must be >= 1, got %d", name, s.Window)
+	}
+	return nil
+}
+
+func (t Threshold) validate(name string) error {
+	var errs []error
+
+	if t.Warning >= t.Danger {
+		errs = append(errs, fmt.Errorf("%s: warning threshold %v must be less than danger threshold %v", name, t.Warning, t.Danger))
+	}
+	if t.Streak < 0 {
+		errs = append(errs, fmt.Errorf("%s: streak must be >= 0, got %d", name, t.Streak))
+	}
+	if t.Decay != DecayStep && t.Decay != DecayReset {
+		errs = append(errs, fmt.Errorf("%s: unknown decay policy %q", name, t.Decay))
+	}
+	if t.WarningExit > t.Warning {
+		errs = append(errs, fmt.Errorf("%s: warning exit threshold %v must not exceed warning threshold %v", name, t.WarningExit, t.Warning))
+	}
+	if t.DangerExit > t.Danger {
+		errs = append(errs, fmt.Errorf("%s: danger exit threshold %v must not exceed danger threshold %v", name, t.DangerExit, t.Danger))
+	}
+	if t.WarningDwell < 0 || t.DangerDwell < 0 {
+		errs = append(errs, fmt.Errorf("%s: dwell time must be >= 0", name))
+	}
+	errs = append(errs, t.Smoothing.validate(name))
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,249 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Checker_ValidateDefault(t *testing.T) {
+	assert.NoError(t, DefaultChecker().Validate())
+}
+
+func Test_Checker_ValidateThresholds(t *testing.T) {
+	c := DefaultChecker()
+	c.CPU.Warning = 95
+	c.RAM.Warning = 95
+	c.Disk.Streak = -1
+
+	err := c.Validate()
+	assert.ErrorContains(t, err, "cpu: warning threshold 95 must be less than danger threshold 90")
+	assert.ErrorContains(t, err, "ram: warning threshold 95 must be less than danger threshold 90")
+	assert.ErrorContains(t, err, "disk: streak must be >= 0")
+	assert.NotContains(t, err.Error(), "net")
+}
+
+func Test_Load_ZeroInterval(t *testing.T) {
+	_, err := Load([]string{"-i", "0"})
+	assert.ErrorContains(t, err, "incorrect interval, please specify > 0")
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func Test_Load_Defaults(t *testing.T) {
+	c, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultChecker(), c)
+}
+
+func Test_Load_YAMLFile(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+interval: 10s
+port: "9090"
+cpu:
+  warning: 60
+`)
+
+	c, err := Load([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, c.Interval)
+	assert.Equal(t, "9090", c.Port)
+	assert.Equal(t, Threshold{Warning: 60, Danger: 90, Streak: 10, Decay: DecayStep, Smoothing: smoothing(1)}, c.CPU)
+	assert.Equal(t, "localhost", c.Address)
+}
+
+func Test_Load_JSONFile(t *testing.T) {
+	path := writeConfig(t, "config.json", "{\n\t\"debug\": true,\n\t\"disk\": {\"warning\": 50, \"danger\": 70, \"streak\": 3}\n}")
+
+	c, err := Load([]string{"-c", path})
+	require.NoError(t, err)
+	assert.True(t, c.DebugMode)
+	assert.Equal(t, Threshold{Warning: 50, Danger: 70, Streak: 3, Decay: DecayStep, Smoothing: smoothing(5)}, c.Disk)
+}
+
+func Test_Load_Precedence(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "port: \"1111\"\naddress: file\ninterval: 1s\n")
+	t.Setenv("PORT", "3333")
+
+	c, err := Load([]string{"-c", path, "-p", "2222", "-a", "flag"})
+	require.NoError(t, err)
+	assert.Equal(t, "3333", c.Port, "env has priority over flags")
+	assert.Equal(t, "flag", c.Address, "flags have priority over the file")
+	assert.Equal(t, time.Second, c.Interval, "file has priority over defaults")
+}
+
+func Test_Load_ConfigFileFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfig(t, "config.yaml", "port: \"4444\"\n"))
+
+	c, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "4444", c.Port)
+}
+
+func Test_Load_InvalidFields(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+interval: soon
+unknown: 1
+ram:
+  warning: 25
+  danger: 10
+`)
+	t.Setenv("NET_STREAK", "many")
+
+	_, err := Load([]string{"-c", path})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "line 2")
+	assert.ErrorContains(t, err, "field unknown not found")
+	assert.ErrorContains(t, err, `parsing "many"`)
+	assert.ErrorContains(t, err, "ram: warning threshold 25 must be less than danger threshold 10")
+}
+
+func Test_Load_MissingFile(t *testing.T) {
+	_, err := Load([]string{"-c", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.ErrorContains(t, err, "config file")
+}
+
+func Test_Load_NetFilters(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "net_include: [\"eth*\", \"en*\"]\nnet_exclude: [docker0]\n")
+	t.Setenv("NET_EXCLUDE", "veth*,br-*")
+
+	c, err := Load([]string{"-c", path, "-net-include", "eth0, wlan*"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eth0", "wlan*"}, c.NetInclude)
+	assert.Equal(t, []string{"veth*", "br-*"}, c.NetExclude)
+
+	_, err = Load([]string{"-net-include", "eth["})
+	assert.ErrorContains(t, err, `net include: pattern "eth["`)
+}
+
+func Test_Load_DiskFree(t *testing.T) {
+	_, err := Load([]string{"-disk-free-interval", "0"})
+	assert.ErrorContains(t, err, "disk free interval must be > 0, got 0s")
+
+	path := writeConfig(t, "config.yaml", `
+disk_free:
+  warning_percent: 20
+  danger_percent: 10
+disk_free_volumes:
+  "C:":
+    warning_bytes: 2000000000
+    danger_bytes: 1000000000
+`)
+	t.Setenv("DISK_FREE_INTERVAL", "30s")
+
+	c, err := Load([]string{"-c", path, "-disk-free-danger-bytes", "500"})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, c.DiskFreeInterval)
+	assert.Equal(t, FreeSpace{WarningPercent: 20, DangerPercent: 10, DangerBytes: 500}, c.FreeSpace("D:"))
+	assert.Equal(t, FreeSpace{WarningBytes: 2000000000, DangerBytes: 1000000000}, c.FreeSpace("C:"))
+
+	_, err = Load([]string{"-disk-free-warning", "5"})
+	assert.ErrorContains(t, err, "disk free: warning threshold 5% must be greater than danger threshold 5%")
+}
+
+func Test_Load_Smoothing(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+net:
+  smoothing:
+    method: percentile
+    window: 10
+`)
+	t.Setenv("CPU_SMOOTHING_METHOD", "ema")
+
+	c, err := Load([]string{"-c", path, "-cpu-smoothing-alpha", "0.2"})
+	require.NoError(t, err)
+	assert.Equal(t, Smoothing{Method: SmoothingPercentile, Window: 10, Alpha: 0.5, Percentile: 95}, c.Net.Smoothing)
+	assert.Equal(t, Smoothing{Method: SmoothingEMA, Window: 1, Alpha: 0.2, Percentile: 95}, c.CPU.Smoothing)
+
+	_, err = Load([]string{"-ram-smoothing", "mode", "-disk-smoothing-window", "0", "-net-smoothing", "ema", "-net-smoothing-alpha", "2"})
+	assert.ErrorContains(t, err, `ram: unknown smoothing method "mode"`)
+	assert.ErrorContains(t, err, "disk: smoothing window must be >= 1, got 0")
+	assert.ErrorContains(t, err, "net: smoothing alpha must be within (0, 1], got 2")
+}
+
+func Test_Checker_ValidateHysteresis(t *testing.T) {
+	c := DefaultChecker()
+	c.CPU.WarningExit = 80
+	c.Net.DangerExit = 95
+	c.Disk.DangerDwell = -time.Second
+
+	err := c.Validate()
+	assert.ErrorContains(t, err, "cpu: warning exit threshold 80 must not exceed warning threshold 75")
+	assert.ErrorContains(t, err, "net: danger exit threshold 95 must not exceed danger threshold 90")
+	assert.ErrorContains(t, err, "disk: dwell time must be >= 0")
+}
+
+func Test_Load_Decay(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "disk:\n  decay: reset\n")
+
+	c, err := Load([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, DecayReset, c.Disk.Decay)
+	assert.Equal(t, DecayStep, c.CPU.Decay)
+
+	_, err = Load([]string{"-net-decay", "linear"})
+	assert.ErrorContains(t, err, `net: unknown decay policy "linear"`)
+}
+
+func Test_Load_Webhooks(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+webhooks:
+  - url: https://hooks.example.com/disks
+    metrics: [disk, disk_free]
+webhook_renotify: 30m
+`)
+	t.Setenv("WEBHOOK_URLS", "http://localhost:9000/alerts")
+
+	c, err := Load([]string{"-c", path, "-webhook-retries", "5"})
+	require.NoError(t, err)
+	assert.Equal(t, []Webhook{{URL: "https://hooks.example.com/disks", Metrics: []string{"disk", "disk_free"}}}, c.Webhooks)
+	assert.Equal(t, []string{"http://localhost:9000/alerts"}, c.WebhookURLs)
+	assert.Equal(t, 5, c.WebhookRetries)
+	assert.Equal(t, 30*time.Minute, c.WebhookRenotify)
+
+	path = writeConfig(t, "invalid.yaml", `
+webhooks:
+  - url: hooks.example.com
+  - url: http://localhost
+    metrics: [gpu]
+`)
+	_, err = Load([]string{"-c", path})
+	assert.ErrorContains(t, err, `webhook "hooks.example.com": an absolute http or https url is expected`)
+	assert.ErrorContains(t, err, `webhook "http://localhost": unknown metric "gpu"`)
+}
+
+func Test_Load_Alertmanager(t *testing.T) {
+	_, err := Load([]string{"-alertmanager-urls", "alertmanager:9093", "-alertmanager-resend", "0s"})
+	assert.ErrorContains(t, err, `alertmanager "alertmanager:9093": an absolute http or https url is expected`)
+	assert.ErrorContains(t, err, "alertmanager resend interval must be > 0, got 0s")
+
+	t.Setenv("ALERTMANAGER_URLS", "http://alertmanager:9093")
+
+	c, err := Load([]string{"-alertmanager-resend", "30s"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://alertmanager:9093"}, c.AlertmanagerURLs)
+	assert.Equal(t, 30*time.Second, c.AlertmanagerResend)
+}
+
+func Test_Load_Storage(t *testing.T) {
+	_, err := Load([]string{"-storage-raw-samples", "0"})
+	assert.ErrorContains(t, err, "storage raw samples must be >= 1, got 0")
+
+	path := writeConfig(t, "config.yaml", "storage_dir: /var/lib/health-checker\n")
+	t.Setenv("STORAGE_RAW_SAMPLES", "100")
+
+	c, err := Load([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/health-checker", c.StorageDir)
+	assert.Equal(t, 100, c.StorageRawSamples)
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"health-checker/internal/configs"
 	"health-checker/internal/models"
 	"health-checker/internal/services"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -14,35 +18,84 @@ var (
 	monitor *services.Monitor
 )
 
+// metricNames are the display names of the metrics.
+var metricNames = map[string]string{
+	models.MetricCPU:      "CPU",
+	models.MetricRAM:      "RAM",
+	models.MetricNet:      "Network",
+	models.MetricDisk:     "Disk",
+	models.MetricDiskFree: "Disk free",
+}
+
+type thresholds struct {
+	Warning float64 `json:"warning"`
+	Danger  float64 `json:"danger"`
+	Streak  int     `json:"streak"`
+	// WarningBytes and DangerBytes are the free space thresholds in bytes.
+	WarningBytes uint64 `json:"warning_bytes,omitempty"`
+	DangerBytes  uint64 `json:"danger_bytes,omitempty"`
+}
+
+type metricStatus struct {
+	Metric     string             `json:"metric"`
+	Name       string             `json:"name"`
+	Value      *float64           `json:"value"`
+	Unit       string             `json:"unit"`
+	Zone       models.Zone        `json:"zone"`
+	Thresholds thresholds         `json:"thresholds"`
+	SampledAt  *time.Time         `json:"sampled_at"`
+	Raw        map[string]float64 `json:"raw,omitempty"`
+	Devices    []deviceStatus     `json:"devices,omitempty"`
+}
+
+type deviceStatus struct {
+	Device    string      `json:"device"`
+	Value     float64     `json:"value"`
+	Zone      models.Zone `json:"zone"`
+	SampledAt time.Time   `json:"sampled_at"`
+	// Thresholds are the thresholds of a volume, which may be overridden
+	// per volume.
+	Thresholds *thresholds `json:"thresholds,omitempty"`
+}
+
+type checkResponse struct {
+	Status  models.Zone    `json:"status"`
+	Metrics []metricStatus `json:"metrics"`
+}
+
 func NewRouter(m *services.Monitor) *http.ServeMux {
 	monitor = m
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/check", checkUtilization)
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/livez", livez)
+	mux.HandleFunc("/readyz", readyz)
+	mux.HandleFunc("/history", history)
+	mux.HandleFunc("/api/v1/series", series)
+	mux.Handle("/metrics", promhttp.HandlerFor(m.Registry(), promhttp.HandlerOpts{}))
 	return mux
 }
 
-func checkUtilization(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-
-	cpuUsage := monitor.GetCPUUtilizationValue()
-	memUsage := monitor.GetRAMUtilizationValue()
-	netUsage := monitor.GetNetUtilizationValue()
-	diskUsage := monitor.GetDiskUtilizationValue()
+func checkUtilization(w http.ResponseWriter, r *http.Request) {
+	resp := newCheckResponse(monitor.Snapshot())
+	code := http.StatusOK
+	if resp.Status == models.DangerZone ||
+		(resp.Status == models.StaleZone && monitor.Config().StaleUnavailable) {
+		code = http.StatusServiceUnavailable
+	}
 
-	if cpuUsage.LoadZone == services.DangerZone ||
-		memUsage.LoadZone == services.DangerZone ||
-		netUsage.LoadZone == services.DangerZone ||
-		diskUsage.LoadZone == services.DangerZone {
-		w.WriteHeader(http.StatusServiceUnavailable)
+	if wantsJSON(r) {
+		writeJSON(w, code, resp)
+		return
 	}
 
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(code)
+
 	html := "<html><head><title>Health Checker</title></head><body><h1>Health Checker</h1><table>"
-	html = writeUtilization(html, "CPU", cpuUsage)
-	html = writeUtilization(html, "RAM", memUsage)
-	html = writeUtilization(html, "Network", netUsage)
-	html = writeUtilization(html, "Disk", diskUsage)
+	for _, m := range resp.Metrics {
+		html = writeUtilization(html, m)
+	}
 
 	html += "</table>"
 
@@ -53,23 +106,134 @@ func checkUtilization(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-func writeUtilization(html string, name string, usage *models.Utilization) string {
+// wantsJSON reports whether the client asked for a JSON document either with
+// the format query parameter or with the Accept header.
+func wantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func newCheckResponse(snap models.Snapshot) checkResponse {
+	resp := checkResponse{
+		Status:  snap.Zone(),
+		Metrics: make([]metricStatus, 0, len(snap.Metrics)),
+	}
+
+	for _, u := range snap.Metrics {
+		resp.Metrics = append(resp.Metrics, newMetricStatus(u, snap.TakenAt))
+	}
+
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, code int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.Error("error while writing response", "error", err)
+	}
+}
+
+func newMetricStatus(u models.Utilization, now time.Time) metricStatus {
+	t := monitor.Threshold(u.Metric)
+	status := metricStatus{
+		Metric: u.Metric,
+		Name:   metricNames[u.Metric],
+		Unit:   u.Unit,
+		Zone:   u.ZoneAt(now),
+		Thresholds: thresholds{
+			Warning: t.Warning,
+			Danger:  t.Danger,
+			Streak:  t.Streak,
+		},
+	}
+
+	if u.Metric == models.MetricDiskFree {
+		status.Thresholds = freeSpaceThresholds(monitor.Config().DiskFree)
+	}
+
+	if !u.UpdatedAt.IsZero() {
+		status.Value = &u.Value
+		status.SampledAt = &u.UpdatedAt
+		status.Raw = u.Raw
+	}
+
+	for _, d := range u.Devices {
+		device := deviceStatus{
+			Device:    d.Device,
+			Value:     d.Value,
+			Zone:      d.ZoneAt(now),
+			SampledAt: d.UpdatedAt,
+		}
+		if u.Metric == models.MetricDiskFree {
+			t := freeSpaceThresholds(monitor.Config().FreeSpace(d.Device))
+			device.Thresholds = &t
+		}
+		status.Devices = append(status.Devices, device)
+	}
+
+	return status
+}
+
+func freeSpaceThresholds(t configs.FreeSpace) thresholds {
+	return thresholds{
+		Warning:      t.WarningPercent,
+		Danger:       t.DangerPercent,
+		WarningBytes: t.WarningBytes,
+		DangerBytes:  t.DangerBytes,
+	}
+}
+
+func writeUtilization(html string, m metricStatus) string {
 	var (
 		color   string
 		message string
+		value   string
 	)
 
-	switch usage.LoadZone {
-	case services.WarningZone:
+	switch m.Zone {
+	case models.WarningZone:
 		color = "orange"
 		message = "Warning: High utilization"
-	case services.DangerZone:
+	case models.DangerZone:
 		color = "red"
 		message = "Danger: Critical utilization"
+	case models.UnknownZone:
+		color = "grey"
+		message = "Unknown: no fresh data"
+	case models.StaleZone:
+		color = "grey"
+		message = "Stale: last sample at " + m.SampledAt.Format(time.RFC3339)
 	default:
 		color = "green"
 	}
 
-	html += fmt.Sprintf("<tr><td>%s</td><td style='color: %s'>%s</td><td>%s</td></tr>", name, color, usage.Value, message)
+	if m.Value != nil {
+		value = fmt.Sprintf("%.*f", 2, *m.Value)
+	}
+
+	html += fmt.Sprintf("<tr><td>%s</td><td style='color: %s'>%s</td><td>%s</td></tr>", m.Name, color, value, message)
+
+	for _, d := range m.Devices {
+		html = writeUtilization(html, metricStatus{
+			Name:      deviceName(m, d.Device),
+			Value:     &d.Value,
+			Zone:      d.Zone,
+			SampledAt: &d.SampledAt,
+		})
+	}
+
 	return html
 }
+
+// deviceName returns the display name of a device of m.
+func deviceName(m metricStatus, device string) string {
+	if m.Metric == models.MetricCPU {
+		return "CPU core " + device
+	}
+	return m.Name + " " + device
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"health-checker/internal/configs"
+	"health-checker/internal/models"
 	"health-checker/internal/services"
 	"net/http"
 	"net/http/httptest"
@@ -10,17 +12,61 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+type fakeCollector struct {
+	metric string
+	value  float64
+}
+
+func (f *fakeCollector) Name() string {
+	return f.metric
+}
+
+func (f *fakeCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	return []models.Reading{{Metric: f.metric, Value: f.value, Unit: models.UnitPercent}}, nil
+}
+
+func testConfig() configs.Checker {
+	cfg := configs.DefaultChecker()
+	cfg.Interval = time.Hour
+	return cfg
+}
+
+func newTestMonitor() *services.Monitor {
+	return newTestMonitorWith(testConfig(), nil)
+}
+
+// newTestMonitorWith creates a monitor reporting values, the metrics missing
+// from values are reported in the normal zone.
+func newTestMonitorWith(cfg configs.Checker, values map[string]float64) *services.Monitor {
+	normal := map[string]float64{
+		models.MetricCPU:      10,
+		models.MetricRAM:      60,
+		models.MetricNet:      5,
+		models.MetricDisk:     1,
+		models.MetricDiskFree: 50,
+	}
+	for metric, value := range values {
+		normal[metric] = value
+	}
+
+	return services.NewMonitor(cfg,
+		&fakeCollector{metric: models.MetricCPU, value: normal[models.MetricCPU]},
+		&fakeCollector{metric: models.MetricRAM, value: normal[models.MetricRAM]},
+		&fakeCollector{metric: models.MetricNet, value: normal[models.MetricNet]},
+		&fakeCollector{metric: models.MetricDisk, value: normal[models.MetricDisk]},
+		&fakeCollector{metric: models.MetricDiskFree, value: normal[models.MetricDiskFree]},
+	)
+}
+
 func Test_CheckUtilization_AllNormal(t *testing.T) {
-	m := &services.Monitor{}
+	m := newTestMonitor()
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
 	defer cancel()
 
-	c := configs.Checker{
-		Interval: time.Microsecond,
-	}
-	m.Start(ctx, c)
+	m.Start(ctx)
 
 	time.Sleep(time.Millisecond * 5)
 
@@ -42,21 +88,18 @@ func Test_CheckUtilization_AllNormal(t *testing.T) {
 }
 
 func Test_CheckUtilization_WithWarningZone(t *testing.T) {
-	m := &services.Monitor{}
+	cfg := testConfig()
+	cfg.Disk.Streak = 1
+	m := newTestMonitorWith(cfg, map[string]float64{models.MetricDisk: 85})
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	c := configs.Checker{
-		Interval: time.Microsecond,
-	}
-	m.Start(ctx, c)
+	m.Start(ctx)
 
 	time.Sleep(time.Millisecond * 5)
 
 	router := NewRouter(m)
 
-	q := m.GetDiskUtilizationValue()
-	q.LoadZone = services.WarningZone
 	req, _ := http.NewRequest("GET", "/check", nil)
 	rr := httptest.NewRecorder()
 
@@ -74,21 +117,16 @@ func Test_CheckUtilization_WithWarningZone(t *testing.T) {
 }
 
 func Test_CheckUtilization_WithDangerZone(t *testing.T) {
-	m := &services.Monitor{}
+	m := newTestMonitorWith(testConfig(), map[string]float64{models.MetricDisk: 95})
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	c := configs.Checker{
-		Interval: time.Microsecond,
-	}
-	m.Start(ctx, c)
+	m.Start(ctx)
 
 	time.Sleep(time.Millisecond * 5)
 
 	router := NewRouter(m)
 
-	q := m.GetDiskUtilizationValue()
-	q.LoadZone = services.DangerZone
 	req, _ := http.NewRequest("GET", "/check", nil)
 	rr := httptest.NewRecorder()
 
@@ -104,3 +142,210 @@ func Test_CheckUtilization_WithDangerZone(t *testing.T) {
 	ctx.Done()
 	time.Sleep(time.Second)
 }
+
+func Test_CheckUtilization_JSON(t *testing.T) {
+	m := newTestMonitor()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/check?format=json", nil),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/check", nil)
+			r.Header.Set("Accept", "application/json")
+			return r
+		}(),
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var resp checkResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, models.NormalZone, resp.Status)
+		require.Len(t, resp.Metrics, 5)
+
+		cpu := resp.Metrics[0]
+		assert.Equal(t, models.MetricCPU, cpu.Metric)
+		require.NotNil(t, cpu.Value)
+		assert.Equal(t, 10.0, *cpu.Value)
+		assert.Equal(t, models.UnitPercent, cpu.Unit)
+		assert.Equal(t, models.NormalZone, cpu.Zone)
+		assert.Equal(t, thresholds{Warning: 75, Danger: 90, Streak: 10}, cpu.Thresholds)
+		assert.NotNil(t, cpu.SampledAt)
+	}
+}
+
+func Test_CheckUtilization_JSONWithDangerZone(t *testing.T) {
+	m := newTestMonitorWith(testConfig(), map[string]float64{models.MetricNet: 95})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	req := httptest.NewRequest("GET", "/check?format=json", nil)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var resp checkResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, models.DangerZone, resp.Status)
+	assert.Equal(t, models.DangerZone, resp.Metrics[2].Zone)
+}
+
+func Test_CheckUtilization_HTMLFormatParam(t *testing.T) {
+	router := NewRouter(newTestMonitor())
+
+	req := httptest.NewRequest("GET", "/check?format=html", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, "text/html", rr.Header().Get("Content-Type"))
+}
+
+func Test_CheckUtilization_StaleMetric(t *testing.T) {
+	for _, staleUnavailable := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.MaxAge = time.Millisecond
+		cfg.StaleUnavailable = staleUnavailable
+		m := services.NewMonitor(cfg, &fakeCollector{metric: models.MetricCPU, value: 10})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		m.Start(ctx)
+
+		time.Sleep(time.Millisecond * 5)
+
+		router := NewRouter(m)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/check?format=json", nil))
+
+		var resp checkResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, models.StaleZone, resp.Status)
+		assert.Equal(t, models.StaleZone, resp.Metrics[0].Zone)
+
+		if staleUnavailable {
+			assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		} else {
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/check", nil))
+		assert.Contains(t, rr.Body.String(), "Stale: last sample at")
+
+		cancel()
+	}
+}
+
+type coreCollector struct{}
+
+func (c *coreCollector) Name() string {
+	return models.MetricCPU
+}
+
+func (c *coreCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	return []models.Reading{
+		{Metric: models.MetricCPU, Device: "0", Value: 30, Unit: models.UnitPercent},
+		{Metric: models.MetricCPU, Device: "1", Value: 10, Unit: models.UnitPercent},
+		{Metric: models.MetricCPU, Value: 20, Unit: models.UnitPercent},
+	}, nil
+}
+
+func Test_CheckUtilization_CPUCores(t *testing.T) {
+	m := services.NewMonitor(testConfig(), &coreCollector{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/check?format=json", nil))
+
+	var resp checkResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Metrics[0].Devices, 2)
+	assert.Equal(t, "0", resp.Metrics[0].Devices[0].Device)
+	assert.Equal(t, 30.0, resp.Metrics[0].Devices[0].Value)
+	assert.Equal(t, models.NormalZone, resp.Metrics[0].Devices[0].Zone)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/check", nil))
+	assert.Contains(t, rr.Body.String(), "CPU core 1")
+}
+
+type volumeCollector struct {
+	free float64
+}
+
+func (c *volumeCollector) Name() string {
+	return models.MetricDiskFree
+}
+
+func (c *volumeCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	r := models.Reading{
+		Metric: models.MetricDiskFree,
+		Device: "C:",
+		Value:  c.free,
+		Unit:   models.UnitPercent,
+		Raw:    map[string]float64{"free_bytes": c.free * 1e9, "size_bytes": 100e9},
+	}
+	host := r
+	host.Device = ""
+	return []models.Reading{r, host}, nil
+}
+
+func Test_CheckUtilization_DiskFull(t *testing.T) {
+	cfg := testConfig()
+	cfg.DiskFreeVolumes = map[string]configs.FreeSpace{"C:": {WarningPercent: 30, DangerPercent: 20, DangerBytes: 1000}}
+	m := services.NewMonitor(cfg, &volumeCollector{free: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/check?format=json", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var resp checkResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	free := resp.Metrics[4]
+	assert.Equal(t, models.MetricDiskFree, free.Metric)
+	assert.Equal(t, models.DangerZone, free.Zone)
+	require.Len(t, free.Devices, 1)
+	assert.Equal(t, "C:", free.Devices[0].Device)
+	assert.Equal(t, models.DangerZone, free.Devices[0].Zone)
+	assert.Equal(t, thresholds{Warning: 15, Danger: 5}, free.Thresholds)
+	assert.Equal(t, &thresholds{Warning: 30, Danger: 20, DangerBytes: 1000}, free.Devices[0].Thresholds, "the thresholds of the volume are overridden")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/check", nil))
+	assert.Contains(t, rr.Body.String(), "Disk free C:")
+}
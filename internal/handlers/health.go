@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// livez reports whether the process and its collector goroutines are alive.
+func livez(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, monitor.Liveness())
+}
+
+// readyz reports whether every collector has a fresh sample and no metric
+// is in the danger zone.
+func readyz(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, monitor.Readiness(time.Now()))
+}
+
+func writeProbe(w http.ResponseWriter, probeErr error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	body := "ok"
+	if probeErr != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		body = probeErr.Error()
+	}
+
+	_, err := fmt.Fprintln(w, body)
+	if err != nil {
+		slog.Error("error while writing response", "error", err)
+	}
+}
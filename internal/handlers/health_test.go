@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"health-checker/internal/models"
+	"health-checker/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingCollector struct{}
+
+func (f *failingCollector) Name() string {
+	return models.MetricDisk
+}
+
+func (f *failingCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	return nil, errors.New("source is gone")
+}
+
+type panickingCollector struct{}
+
+func (p *panickingCollector) Name() string {
+	return models.MetricCPU
+}
+
+func (p *panickingCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	panic("broken collector")
+}
+
+func serve(router http.Handler, path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+	return rr
+}
+
+func Test_Probes_BeforeFirstSample(t *testing.T) {
+	router := NewRouter(newTestMonitor())
+
+	assert.Equal(t, http.StatusOK, serve(router, "/livez").Code)
+
+	rr := serve(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "collector cpu has no samples yet")
+}
+
+func Test_Probes_Ready(t *testing.T) {
+	m := newTestMonitor()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	assert.Equal(t, http.StatusOK, serve(router, "/livez").Code)
+
+	rr := serve(router, "/readyz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "ok\n", rr.Body.String())
+}
+
+func Test_Probes_DangerZoneNotReady(t *testing.T) {
+	cfg := testConfig()
+	m := services.NewMonitor(cfg, &fakeCollector{metric: models.MetricCPU, value: 99})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	assert.Equal(t, http.StatusOK, serve(router, "/livez").Code)
+
+	rr := serve(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "metric cpu is in the danger zone")
+}
+
+func Test_Probes_DeadCollector(t *testing.T) {
+	cfg := testConfig()
+	m := services.NewMonitor(cfg, &panickingCollector{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	rr := serve(router, "/livez")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "collector cpu stopped: panic: broken collector")
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router, "/readyz").Code)
+}
+
+func Test_Probes_FailingCollector(t *testing.T) {
+	cfg := testConfig()
+	m := services.NewMonitor(cfg, &failingCollector{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	router := NewRouter(m)
+
+	assert.Equal(t, http.StatusOK, serve(router, "/livez").Code)
+
+	rr := serve(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "collector disk is failing: source is gone")
+
+	rr = serve(router, "/check")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unknown: no fresh data")
+}
+
+func Test_Metrics(t *testing.T) {
+	m := newTestMonitor()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	m.Start(ctx)
+
+	time.Sleep(time.Millisecond * 5)
+
+	rr := serve(NewRouter(m), "/metrics")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `health_checker_zone{metric="cpu"} 1`)
+	assert.Contains(t, rr.Body.String(), `health_checker_utilization{metric="cpu"} 10`)
+	assert.Contains(t, rr.Body.String(), `health_checker_collection_duration_seconds_count{collector="cpu"} 1`)
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"health-checker/internal/models"
+	"net/http"
+)
+
+type historyResponse struct {
+	Transitions []models.Transition `json:"transitions"`
+}
+
+// history returns the last zone transitions of the metrics from the oldest
+// to the newest.
+func history(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, historyResponse{Transitions: monitor.History()})
+}
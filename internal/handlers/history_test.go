@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"health-checker/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_History(t *testing.T) {
+	m := newTestMonitorWith(testConfig(), map[string]float64{models.MetricCPU: 95})
+	router := NewRouter(m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m.Start(ctx)
+
+	assert.Eventually(t, func() bool {
+		return len(m.History()) > 0
+	}, time.Second, time.Millisecond)
+
+	rr := serve(router, "/history")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var resp struct {
+		Transitions []models.Transition `json:"transitions"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Transitions, 1)
+	assert.Equal(t, models.MetricCPU, resp.Transitions[0].Metric)
+	assert.Equal(t, models.NormalZone, resp.Transitions[0].From)
+	assert.Equal(t, models.DangerZone, resp.Transitions[0].To)
+	assert.Equal(t, 95.0, resp.Transitions[0].Value)
+	assert.False(t, resp.Transitions[0].At.IsZero())
+}
+
+func Test_History_Empty(t *testing.T) {
+	rr := serve(NewRouter(newTestMonitor()), "/history")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"transitions": []}`, rr.Body.String())
+}
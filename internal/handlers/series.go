@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"health-checker/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRange is the range of a query without from.
+	defaultRange = time.Hour
+	// defaultStep is the step of a query without step.
+	defaultStep = time.Minute
+	// maxPoints limits the number of points of a query.
+	maxPoints = 11000
+)
+
+type seriesResponse struct {
+	Metric     string `json:"metric"`
+	Resolution string `json:"resolution"`
+	// Step is the duration of a point in seconds.
+	Step   float64         `json:"step"`
+	Points []storage.Point `json:"points"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// series returns the stored samples of a metric aggregated into points of
+// step within [from, to]. The times are RFC 3339 or unix seconds, the step
+// is a duration or seconds.
+func series(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	metric := q.Get("metric")
+	if _, ok := metricNames[metric]; !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("unknown metric %q", metric)})
+		return
+	}
+
+	to, err := parseTime(q.Get("to"), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("to: %v", err)})
+		return
+	}
+	from, err := parseTime(q.Get("from"), to.Add(-defaultRange))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("from: %v", err)})
+		return
+	}
+	step, err := parseStep(q.Get("step"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("step: %v", err)})
+		return
+	}
+
+	switch {
+	case from.After(to):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "from must not be after to"})
+		return
+	case to.Sub(from)/step > maxPoints:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("more than %d points, increase the step", maxPoints)})
+		return
+	}
+
+	points, res, err := monitor.Store().Query(metric, from, to, step)
+	if err != nil {
+		slog.Error("series query failed", "metric", metric, "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, seriesResponse{
+		Metric:     metric,
+		Resolution: res.Name,
+		Step:       step.Seconds(),
+		Points:     points,
+	})
+}
+
+// parseTime parses an RFC 3339 time or unix seconds, def when value is empty.
+func parseTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return time.Unix(0, int64(seconds*1e9)), nil
+}
+
+// parseStep parses a positive duration or seconds, defaultStep when value is
+// empty.
+func parseStep(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultStep, nil
+	}
+
+	step, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, ferr := strconv.ParseFloat(value, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		step = time.Duration(seconds * float64(time.Second))
+	}
+	if step <= 0 {
+		return 0, fmt.Errorf("must be > 0, got %q", value)
+	}
+	return step, nil
+}
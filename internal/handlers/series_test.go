@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"health-checker/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Series(t *testing.T) {
+	m := newTestMonitor()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, m.Store().Add(models.MetricCPU, start.Add(time.Duration(i)*30*time.Second), float64(i)))
+	}
+
+	path := fmt.Sprintf("/api/v1/series?metric=cpu&from=%d&to=%s&step=2m", start.Unix(), start.Add(time.Hour).Format(time.RFC3339))
+	rr := serve(NewRouter(m), path)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp seriesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "cpu", resp.Metric)
+	assert.Equal(t, "1m", resp.Resolution)
+	assert.Equal(t, 120.0, resp.Step)
+	require.Len(t, resp.Points, 3)
+	assert.True(t, resp.Points[0].Time.Equal(start))
+	assert.Equal(t, 1.5, resp.Points[0].Value)
+	assert.Equal(t, 3.0, resp.Points[0].Max)
+	assert.Equal(t, uint64(4), resp.Points[0].Count)
+	assert.Equal(t, 8.5, resp.Points[2].Value)
+}
+
+func Test_Series_Defaults(t *testing.T) {
+	m := newTestMonitor()
+	require.NoError(t, m.Store().Add(models.MetricRAM, time.Now().Add(-2*time.Hour), 10))
+	require.NoError(t, m.Store().Add(models.MetricRAM, time.Now(), 20))
+
+	rr := serve(NewRouter(m), "/api/v1/series?metric=ram")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp seriesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 60.0, resp.Step)
+	require.Len(t, resp.Points, 1, "the last hour is returned")
+	assert.Equal(t, 20.0, resp.Points[0].Value)
+}
+
+func Test_Series_InvalidQuery(t *testing.T) {
+	router := NewRouter(newTestMonitor())
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"metric=gpu", `unknown metric \"gpu\"`},
+		{"metric=cpu&from=yesterday", `from: invalid time \"yesterday\"`},
+		{"metric=cpu&step=-1m", `step: must be \u003e 0, got \"-1m\"`},
+		{"metric=cpu&from=2000&to=1000", "from must not be after to"},
+		{"metric=cpu&from=0&to=1000000&step=1s", "more than 11000 points"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := serve(router, "/api/v1/series?"+tt.query)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.want)
+		})
+	}
+}
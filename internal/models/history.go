@@ -0,0 +1,45 @@
+package models
+
+import "sync"
+
+// History keeps the last transitions. It is safe for concurrent use.
+type History struct {
+	mu          sync.Mutex
+	transitions []Transition
+	size        int
+	// next is the position of the next transition once the history is full.
+	next int
+}
+
+// NewHistory creates a history of the last size transitions, at least one.
+func NewHistory(size int) *History {
+	size = max(size, 1)
+	return &History{
+		transitions: make([]Transition, 0, size),
+		size:        size,
+	}
+}
+
+// Add appends t, dropping the oldest transition when the history is full.
+func (h *History) Add(t Transition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.transitions) < h.size {
+		h.transitions = append(h.transitions, t)
+		return
+	}
+
+	h.transitions[h.next] = t
+	h.next = (h.next + 1) % h.size
+}
+
+// Transitions returns a copy of the transitions from the oldest to the newest.
+func (h *History) Transitions() []Transition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	transitions := make([]Transition, 0, len(h.transitions))
+	transitions = append(transitions, h.transitions[h.next:]...)
+	return append(transitions, h.transitions[:h.next]...)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_History_Transitions(t *testing.T) {
+	h := NewHistory(3)
+	assert.Empty(t, h.Transitions())
+
+	for i := 1; i < 6; i++ {
+		h.Add(Transition{Metric: MetricCPU, Value: float64(i)})
+	}
+
+	var values []float64
+	for _, tr := range h.Transitions() {
+		values = append(values, tr.Value)
+	}
+	assert.Equal(t, []float64{3, 4, 5}, values, "the oldest transitions are dropped")
+}
+
+func Test_History_TransitionsIsCopy(t *testing.T) {
+	h := NewHistory(2)
+	h.Add(Transition{Metric: MetricCPU, To: DangerZone})
+
+	transitions := h.Transitions()
+	transitions[0].To = NormalZone
+
+	assert.Equal(t, DangerZone, h.Transitions()[0].To)
+}
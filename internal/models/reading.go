@@ -0,0 +1,25 @@
+package models
+
+const (
+	MetricCPU      = "cpu"
+	MetricRAM      = "ram"
+	MetricNet      = "net"
+	MetricDisk     = "disk"
+	MetricDiskFree = "disk_free"
+)
+
+const (
+	UnitPercent = "percent"
+)
+
+// Reading is a single value produced by a collector.
+// Device identifies the volume, core or interface the value belongs to
+// and is empty for host-wide values.
+type Reading struct {
+	Metric string
+	Device string
+	Value  float64
+	Unit   string
+	// Raw holds the source values the reading was computed from.
+	Raw map[string]float64
+}
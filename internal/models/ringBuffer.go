@@ -1,5 +1,10 @@
 package models
 
+import (
+	"math"
+	"slices"
+)
+
 type RingBuffer struct {
 	data  []float64
 	size  int
@@ -7,7 +12,9 @@ type RingBuffer struct {
 	end   int
 }
 
+// NewRingBuffer creates a buffer of the last size values, at least one.
 func NewRingBuffer(size int) *RingBuffer {
+	size = max(size, 1)
 	return &RingBuffer{
 		data: make([]float64, 0, size),
 		size: size,
@@ -31,11 +38,30 @@ func (rb *RingBuffer) get() []float64 {
 	return rb.data
 }
 
+// GetAverage returns the mean of the values, zero when there are none.
 func (rb *RingBuffer) GetAverage() float64 {
-	sum := 0.0
 	values := rb.get()
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
 	for _, v := range values {
 		sum += v
 	}
 	return sum / float64(len(values))
 }
+
+// GetPercentile returns the p-th percentile of the values interpolated
+// between the closest ranks, zero when there are none.
+func (rb *RingBuffer) GetPercentile(p float64) float64 {
+	values := slices.Clone(rb.get())
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+
+	rank := p / 100 * float64(len(values)-1)
+	lower, upper := int(math.Floor(rank)), int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
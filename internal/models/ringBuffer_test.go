@@ -49,3 +49,32 @@ func Test_RingBuffer_AverageWith10Values(t *testing.T) {
 	avg := rb.GetAverage()
 	assert.Equal(t, 8.0, avg, "Ожидаемое среднее 8.0")
 }
+
+func Test_RingBuffer_AverageEmpty(t *testing.T) {
+	rb := NewRingBuffer(3)
+
+	assert.Equal(t, 0.0, rb.GetAverage())
+	assert.Equal(t, 0.0, rb.GetPercentile(95))
+}
+
+func Test_RingBuffer_ZeroSize(t *testing.T) {
+	rb := NewRingBuffer(0)
+
+	rb.Add(1)
+	rb.Add(2)
+
+	assert.Equal(t, []float64{2}, rb.get())
+}
+
+func Test_RingBuffer_Percentile(t *testing.T) {
+	rb := NewRingBuffer(5)
+
+	for _, v := range []float64{40, 10, 30, 20, 50} {
+		rb.Add(v)
+	}
+
+	assert.Equal(t, 30.0, rb.GetPercentile(50))
+	assert.Equal(t, 10.0, rb.GetPercentile(0))
+	assert.Equal(t, 50.0, rb.GetPercentile(100))
+	assert.InDelta(t, 48.0, rb.GetPercentile(95), 0.001)
+}
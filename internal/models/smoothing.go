@@ -0,0 +1,64 @@
+package models
+
+// Smoother smooths a stream of samples, so that a single spike does not
+// move a metric to another zone.
+type Smoother interface {
+	// Add appends value and returns the smoothed value.
+	Add(value float64) float64
+}
+
+// SMA is the simple moving average of the last window samples.
+type SMA struct {
+	buf *RingBuffer
+}
+
+func NewSMA(window int) *SMA {
+	return &SMA{buf: NewRingBuffer(window)}
+}
+
+func (s *SMA) Add(value float64) float64 {
+	s.buf.Add(value)
+	return s.buf.GetAverage()
+}
+
+// EMA is the exponential moving average of the samples, alpha is the weight
+// of the newest sample in (0, 1].
+type EMA struct {
+	alpha   float64
+	value   float64
+	started bool
+}
+
+func NewEMA(alpha float64) *EMA {
+	return &EMA{alpha: alpha}
+}
+
+func (e *EMA) Add(value float64) float64 {
+	if !e.started {
+		e.value, e.started = value, true
+		return e.value
+	}
+
+	e.value = e.alpha*value + (1-e.alpha)*e.value
+	return e.value
+}
+
+// Percentile is the p-th percentile of the last window samples.
+type Percentile struct {
+	buf *RingBuffer
+	p   float64
+}
+
+func NewPercentile(window int, p float64) *Percentile {
+	return &Percentile{buf: NewRingBuffer(window), p: p}
+}
+
+// NewMedian returns the median of the last window samples.
+func NewMedian(window int) *Percentile {
+	return NewPercentile(window, 50)
+}
+
+func (s *Percentile) Add(value float64) float64 {
+	s.buf.Add(value)
+	return s.buf.GetPercentile(s.p)
+}
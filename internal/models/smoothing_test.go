@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func smooth(s Smoother, values ...float64) []float64 {
+	out := make([]float64, 0, len(values))
+	for _, v := range values {
+		out = append(out, s.Add(v))
+	}
+	return out
+}
+
+func Test_SMA(t *testing.T) {
+	assert.Equal(t, []float64{10, 15, 20, 30}, smooth(NewSMA(3), 10, 20, 30, 40))
+}
+
+func Test_EMA(t *testing.T) {
+	assert.Equal(t, []float64{10, 15, 22.5}, smooth(NewEMA(0.5), 10, 20, 30))
+}
+
+func Test_Median(t *testing.T) {
+	// the spike does not move the median
+	assert.Equal(t, []float64{10, 55, 10, 10}, smooth(NewMedian(3), 10, 100, 10, 10))
+}
+
+func Test_Percentile(t *testing.T) {
+	got := smooth(NewPercentile(5, 95), 10, 10, 10, 10, 100)
+	assert.InDelta(t, 82.0, got[4], 0.001)
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Snapshot is a copy of the utilization of every metric taken at TakenAt.
+type Snapshot struct {
+	TakenAt time.Time
+	Metrics []Utilization
+}
+
+// Get returns the utilization of metric.
+func (s Snapshot) Get(metric string) (Utilization, bool) {
+	for _, u := range s.Metrics {
+		if u.Metric == metric {
+			return u, true
+		}
+	}
+	return Utilization{}, false
+}
+
+// Zone returns the worst zone of all metrics at TakenAt, NormalZone if
+// there are no samples yet.
+func (s Snapshot) Zone() Zone {
+	zone := NormalZone
+	for _, u := range s.Metrics {
+		zone = max(zone, u.ZoneAt(s.TakenAt))
+	}
+	return zone
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot_Zone(t *testing.T) {
+	now := time.Now()
+
+	snap := Snapshot{TakenAt: now}
+	assert.Equal(t, NormalZone, snap.Zone())
+
+	snap.Metrics = []Utilization{
+		{Metric: MetricCPU, LoadZone: WarningZone, UpdatedAt: now},
+		{Metric: MetricRAM, LoadZone: PendingZone},
+	}
+	assert.Equal(t, WarningZone, snap.Zone())
+
+	snap.Metrics = append(snap.Metrics, Utilization{
+		Metric:    MetricDisk,
+		LoadZone:  NormalZone,
+		UpdatedAt: now.Add(-time.Hour),
+		MaxAge:    time.Minute,
+	})
+	assert.Equal(t, StaleZone, snap.Zone())
+}
+
+func Test_Snapshot_Get(t *testing.T) {
+	snap := Snapshot{Metrics: []Utilization{{Metric: MetricCPU, Value: 42}}}
+
+	u, ok := snap.Get(MetricCPU)
+	assert.True(t, ok)
+	assert.Equal(t, 42.0, u.Value)
+
+	_, ok = snap.Get(MetricNet)
+	assert.False(t, ok)
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Transition is a change of the zone of a metric.
+type Transition struct {
+	Metric string `json:"metric"`
+	From   Zone   `json:"from"`
+	To     Zone   `json:"to"`
+	// Value is the smoothed value that caused the transition.
+	Value float64   `json:"value"`
+	At    time.Time `json:"time"`
+}
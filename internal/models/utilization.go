@@ -1,9 +1,49 @@
 package models
 
-import "sync"
+import (
+	"strconv"
+	"time"
+)
 
 type Utilization struct {
-	sync.Mutex
-	Value    string
-	LoadZone string
+	Metric string
+	// Device is the core, interface, disk or volume the value belongs to,
+	// empty for host-wide values.
+	Device   string
+	Value    float64
+	Unit     string
+	LoadZone Zone
+	// Raw holds the source values of the last sample.
+	Raw map[string]float64
+	// UpdatedAt is the time of the last sample, zero until the first one.
+	UpdatedAt time.Time
+	// MaxAge is the age after which the last sample is considered stale.
+	MaxAge time.Duration
+	// Devices holds the per-device utilization of a host-wide metric.
+	Devices []Utilization
+}
+
+// IsStale reports whether the last sample is older than MaxAge at now.
+// A utilization without samples is not stale.
+func (u Utilization) IsStale(now time.Time) bool {
+	return !u.UpdatedAt.IsZero() && u.MaxAge > 0 && now.Sub(u.UpdatedAt) > u.MaxAge
+}
+
+// ZoneAt returns the load zone at now, StaleZone if the last sample is stale.
+func (u Utilization) ZoneAt(now time.Time) Zone {
+	if u.IsStale(now) {
+		return StaleZone
+	}
+	return u.LoadZone
+}
+
+// LessDevice orders device names numerically when both are numbers, so
+// that core 10 follows core 9, and lexically otherwise.
+func LessDevice(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	if errX == nil && errY == nil {
+		return x < y
+	}
+	return a < b
 }
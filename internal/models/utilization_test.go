@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Utilization_IsStale(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		u    Utilization
+		want bool
+	}{
+		{"no samples", Utilization{MaxAge: time.Minute}, false},
+		{"fresh", Utilization{UpdatedAt: now.Add(-time.Second), MaxAge: time.Minute}, false},
+		{"stale", Utilization{UpdatedAt: now.Add(-2 * time.Minute), MaxAge: time.Minute}, true},
+		{"no max age", Utilization{UpdatedAt: now.Add(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.u.IsStale(now))
+		})
+	}
+}
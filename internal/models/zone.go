@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Zone is the load zone of a metric. Zones are ordered by severity, so the
+// worst of several zones is the greatest one.
+type Zone int
+
+const (
+	// PendingZone is the zone of a metric without samples yet.
+	PendingZone Zone = iota
+	NormalZone
+	// UnknownZone is reported while the collector of a metric is failing.
+	UnknownZone
+	WarningZone
+	// StaleZone is reported when the last sample of a metric is older than
+	// its max age.
+	StaleZone
+	DangerZone
+)
+
+var zoneNames = map[Zone]string{
+	PendingZone: "pending",
+	NormalZone:  "normal",
+	UnknownZone: "unknown",
+	WarningZone: "warning",
+	StaleZone:   "stale",
+	DangerZone:  "danger",
+}
+
+func (z Zone) String() string {
+	if name, ok := zoneNames[z]; ok {
+		return name
+	}
+	return fmt.Sprintf("Zone(%d)", int(z))
+}
+
+func (z Zone) MarshalJSON() ([]byte, error) {
+	return json.Marshal(z.String())
+}
+
+func (z *Zone) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	for zone, n := range zoneNames {
+		if n == name {
+			*z = zone
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown zone %q", name)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Zone_String(t *testing.T) {
+	assert.Equal(t, "pending", PendingZone.String())
+	assert.Equal(t, "danger", DangerZone.String())
+	assert.Equal(t, "Zone(42)", Zone(42).String())
+}
+
+func Test_Zone_JSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Zone{"zone": WarningZone})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"zone": "warning"}`, string(data))
+
+	var z Zone
+	require.NoError(t, json.Unmarshal([]byte(`"stale"`), &z))
+	assert.Equal(t, StaleZone, z)
+
+	assert.Error(t, json.Unmarshal([]byte(`"purple"`), &z))
+}
+
+func Test_Zone_Severity(t *testing.T) {
+	assert.Greater(t, DangerZone, StaleZone)
+	assert.Greater(t, StaleZone, WarningZone)
+	assert.Greater(t, WarningZone, UnknownZone)
+	assert.Greater(t, UnknownZone, NormalZone)
+	assert.Greater(t, NormalZone, PendingZone)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"health-checker/internal/models"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// alertName is the name of the alerts pushed to Alertmanager.
+const alertName = "HealthCheckerZone"
+
+// alert is an alert of the Alertmanager v2 API.
+type alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	// EndsAt is set once the alert is resolved.
+	EndsAt *time.Time `json:"endsAt,omitempty"`
+}
+
+// alertmanager pushes the metrics in the warning and danger zones to the
+// Alertmanager v2 API. A metric moving to another zone resolves the alert of
+// the previous zone, so every zone is a separate alert.
+type alertmanager struct {
+	poster
+	host   string
+	resend time.Duration
+
+	// firing are the alerts of the metrics in the warning and danger zones.
+	firing map[string]alert
+}
+
+func (a *alertmanager) routes(string) bool {
+	return true
+}
+
+// run pushes the alerts on every transition and pushes the firing ones again
+// every resend interval, so that Alertmanager does not resolve them.
+func (a *alertmanager) run(ctx context.Context, transitions <-chan models.Transition) {
+	ticker := time.NewTicker(a.resend)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-transitions:
+			a.notify(ctx, t)
+		case <-ticker.C:
+			a.resendFiring(ctx)
+		}
+	}
+}
+
+// notify resolves the alert of the zone the metric of t leaves and fires an
+// alert for the zone it enters, if it is the warning or danger zone.
+func (a *alertmanager) notify(ctx context.Context, t models.Transition) {
+	var alerts []alert
+
+	if prev, ok := a.firing[t.Metric]; ok {
+		prev.EndsAt = &t.At
+		alerts = append(alerts, prev)
+		delete(a.firing, t.Metric)
+	}
+	if t.To == models.WarningZone || t.To == models.DangerZone {
+		firing := a.newAlert(t)
+		a.firing[t.Metric] = firing
+		alerts = append(alerts, firing)
+	}
+
+	a.push(ctx, alerts)
+}
+
+func (a *alertmanager) newAlert(t models.Transition) alert {
+	return alert{
+		Labels: map[string]string{
+			"alertname": alertName,
+			"host":      a.host,
+			"metric":    t.Metric,
+			"zone":      t.To.String(),
+		},
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("%s is in the %s zone on %s", t.Metric, t.To, a.host),
+			"value":   fmt.Sprintf("%.*f", 2, t.Value),
+		},
+		StartsAt: t.At,
+	}
+}
+
+func (a *alertmanager) resendFiring(ctx context.Context) {
+	alerts := make([]alert, 0, len(a.firing))
+	for _, firing := range a.firing {
+		alerts = append(alerts, firing)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Labels["metric"] < alerts[j].Labels["metric"]
+	})
+
+	a.push(ctx, alerts)
+}
+
+// push delivers alerts. An alert that fails to fire is pushed again by the
+// next resend, a resolution that fails is left to the resolve timeout of
+// Alertmanager.
+func (a *alertmanager) push(ctx context.Context, alerts []alert) {
+	if len(alerts) == 0 {
+		return
+	}
+
+	if err := a.deliver(ctx, alerts); err != nil {
+		slog.Error("alertmanager delivery failed", "url", a.url, "alerts", len(alerts), "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func alertmanagerConfig(url string) configs.Checker {
+	cfg := configs.DefaultChecker()
+	cfg.AlertmanagerURLs = []string{url}
+	cfg.WebhookBackoff = time.Millisecond
+	cfg.RetryMaxBackoff = 5 * time.Millisecond
+	return cfg
+}
+
+func Test_Alertmanager_FireAndResolve(t *testing.T) {
+	am, srv := newEndpoint[[]alert](t)
+	transitions := runNotifier(t, alertmanagerConfig(srv.URL+"/"))
+	host, _ := os.Hostname()
+
+	warning := transition(models.MetricCPU, models.NormalZone, models.WarningZone)
+	danger := transition(models.MetricCPU, models.WarningZone, models.DangerZone)
+	danger.At = warning.At.Add(time.Minute)
+	normal := transition(models.MetricCPU, models.DangerZone, models.NormalZone)
+	normal.At = danger.At.Add(time.Minute)
+	transitions <- warning
+	transitions <- danger
+	transitions <- normal
+
+	require.Eventually(t, func() bool {
+		return len(am.received()) == 3
+	}, time.Second, time.Millisecond)
+	pushes := am.received()
+	assert.Equal(t, "/api/v2/alerts", am.path)
+
+	require.Len(t, pushes[0], 1)
+	fired := pushes[0][0]
+	assert.Equal(t, map[string]string{"alertname": alertName, "host": host, "metric": "cpu", "zone": "warning"}, fired.Labels)
+	assert.Equal(t, "95.00", fired.Annotations["value"])
+	assert.Equal(t, warning.At, fired.StartsAt)
+	assert.Nil(t, fired.EndsAt)
+
+	require.Len(t, pushes[1], 2, "the warning alert is resolved and the danger alert fires")
+	assert.Equal(t, "warning", pushes[1][0].Labels["zone"])
+	require.NotNil(t, pushes[1][0].EndsAt)
+	assert.Equal(t, danger.At, *pushes[1][0].EndsAt)
+	assert.Equal(t, "danger", pushes[1][1].Labels["zone"])
+	assert.Equal(t, danger.At, pushes[1][1].StartsAt)
+
+	require.Len(t, pushes[2], 1)
+	assert.Equal(t, "danger", pushes[2][0].Labels["zone"])
+	require.NotNil(t, pushes[2][0].EndsAt)
+	assert.Equal(t, normal.At, *pushes[2][0].EndsAt)
+}
+
+func Test_Alertmanager_Resend(t *testing.T) {
+	am, srv := newEndpoint[[]alert](t)
+	cfg := alertmanagerConfig(srv.URL)
+	cfg.AlertmanagerResend = 10 * time.Millisecond
+	transitions := runNotifier(t, cfg)
+
+	transitions <- transition(models.MetricDisk, models.NormalZone, models.DangerZone)
+	transitions <- transition(models.MetricRAM, models.NormalZone, models.WarningZone)
+
+	require.Eventually(t, func() bool {
+		pushes := am.received()
+		return len(pushes) > 2 && len(pushes[len(pushes)-1]) == 2
+	}, time.Second, time.Millisecond)
+	pushes := am.received()
+	resent := pushes[len(pushes)-1]
+	assert.Equal(t, "disk", resent[0].Labels["metric"])
+	assert.Equal(t, "ram", resent[1].Labels["metric"])
+	assert.Nil(t, resent[0].EndsAt)
+
+	transitions <- transition(models.MetricDisk, models.DangerZone, models.NormalZone)
+	transitions <- transition(models.MetricRAM, models.WarningZone, models.NormalZone)
+	require.Eventually(t, func() bool {
+		pushes := am.received()
+		return len(pushes[len(pushes)-1]) == 1 && pushes[len(pushes)-1][0].Labels["metric"] == "ram"
+	}, time.Second, time.Millisecond)
+
+	pushed := len(am.received())
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, am.received(), pushed, "resolved alerts are not resent")
+}
+
+func Test_Alertmanager_NormalNotPushed(t *testing.T) {
+	am, srv := newEndpoint[[]alert](t)
+	transitions := runNotifier(t, alertmanagerConfig(srv.URL))
+
+	transitions <- transition(models.MetricNet, models.StaleZone, models.NormalZone)
+	transitions <- transition(models.MetricNet, models.NormalZone, models.DangerZone)
+
+	require.Eventually(t, func() bool {
+		return len(am.received()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "danger", am.received()[0][0].Labels["zone"])
+	assert.Equal(t, 1, am.attempts())
+}
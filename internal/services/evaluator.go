@@ -0,0 +1,193 @@
+package services
+
+import (
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"math"
+	"time"
+)
+
+// direction is the direction in which the values of a metric get worse.
+type direction int
+
+const (
+	// rising values are worse, e.g. utilization.
+	rising direction = iota
+	// falling values are worse, e.g. free space.
+	falling
+)
+
+// decay is how the streak of an evaluator is lost below the warning threshold.
+type decay int
+
+const (
+	// decayStep loses one value of the streak for every value below the
+	// warning threshold.
+	decayStep decay = iota
+	// decayReset loses the whole streak at the first value below the
+	// warning threshold.
+	decayReset
+)
+
+// zoneRule describes how the values of a metric are mapped to load zones.
+type zoneRule struct {
+	direction direction
+	warning   float64
+	danger    float64
+	// streak is the number of values above the warning threshold before
+	// the warning zone is entered.
+	streak int
+	decay  decay
+	// warningExit and dangerExit are the thresholds to leave the zones.
+	warningExit float64
+	dangerExit  float64
+	// warningDwell and dangerDwell are the minimum time spent in the zones.
+	warningDwell time.Duration
+	dangerDwell  time.Duration
+	// smoothing is applied to the values before the zone is evaluated.
+	smoothing configs.Smoothing
+}
+
+func newZoneRule(t configs.Threshold) zoneRule {
+	rule := zoneRule{
+		warning:      t.Warning,
+		danger:       t.Danger,
+		streak:       t.Streak,
+		warningExit:  t.WarningExit,
+		dangerExit:   t.DangerExit,
+		warningDwell: t.WarningDwell,
+		dangerDwell:  t.DangerDwell,
+		smoothing:    t.Smoothing,
+	}
+	if t.Decay == configs.DecayReset {
+		rule.decay = decayReset
+	}
+	if rule.warningExit == 0 {
+		rule.warningExit = rule.warning
+	}
+	if rule.dangerExit == 0 {
+		rule.dangerExit = rule.danger
+	}
+	return rule
+}
+
+// fallingRule returns a rule entering the zones as soon as a value drops to
+// the thresholds and leaving them once it rises above, zero disables a
+// threshold.
+func fallingRule(warning, danger float64) zoneRule {
+	disabled := func(threshold float64) float64 {
+		if threshold == 0 {
+			return math.Inf(-1)
+		}
+		return threshold
+	}
+
+	return zoneRule{
+		direction:   falling,
+		warning:     disabled(warning),
+		danger:      disabled(danger),
+		warningExit: disabled(warning),
+		dangerExit:  disabled(danger),
+		decay:       decayReset,
+		smoothing:   configs.Smoothing{Method: configs.SmoothingSMA, Window: 1},
+	}
+}
+
+// reached reports whether value crossed threshold in the direction of the rule.
+func (r zoneRule) reached(value, threshold float64) bool {
+	if r.direction == falling {
+		return value <= threshold
+	}
+	return value >= threshold
+}
+
+// dwell returns the minimum time spent in zone.
+func (r zoneRule) dwell(zone models.Zone) time.Duration {
+	switch zone {
+	case models.WarningZone:
+		return r.warningDwell
+	case models.DangerZone:
+		return r.dangerDwell
+	}
+	return 0
+}
+
+// newSmoother returns the smoother selected by s, the simple moving average
+// for an unknown method.
+func newSmoother(s configs.Smoothing) models.Smoother {
+	switch s.Method {
+	case configs.SmoothingEMA:
+		return models.NewEMA(s.Alpha)
+	case configs.SmoothingMedian:
+		return models.NewMedian(s.Window)
+	case configs.SmoothingPercentile:
+		return models.NewPercentile(s.Window, s.Percentile)
+	default:
+		return models.NewSMA(s.Window)
+	}
+}
+
+// evaluator maps a stream of values to zones according to a rule. It starts
+// in the normal zone.
+type evaluator struct {
+	// metric is the metric of the transitions.
+	metric   string
+	rule     zoneRule
+	smoother models.Smoother
+	// highLoadCounter is the streak of values above the warning threshold.
+	highLoadCounter int
+	// zone is the zone of the last value, entered at since.
+	zone  models.Zone
+	since time.Time
+}
+
+func newEvaluator(metric string, rule zoneRule) *evaluator {
+	return &evaluator{metric: metric, rule: rule, smoother: newSmoother(rule.smoothing), zone: models.NormalZone}
+}
+
+// add appends value sampled at now and returns the smoothed value, its zone
+// and the transition to the zone, nil when the zone has not changed. The zone
+// is not better than floor, e.g. the worst zone of the devices of the metric.
+// A zone is left once the value drops below its exit threshold and the dwell
+// time of the zone has passed, a worse zone is entered at once.
+func (e *evaluator) add(value float64, floor models.Zone, now time.Time) (float64, models.Zone, *models.Transition) {
+	rule := e.rule
+	avg := e.smoother.Add(value)
+
+	warning, danger := rule.warning, rule.danger
+	if e.zone >= models.WarningZone {
+		warning = rule.warningExit
+	}
+	if e.zone == models.DangerZone {
+		danger = rule.dangerExit
+	}
+
+	switch {
+	case rule.reached(avg, warning):
+		e.highLoadCounter++
+	case rule.decay == decayReset:
+		e.highLoadCounter = 0
+	case e.highLoadCounter > 0:
+		e.highLoadCounter--
+	}
+
+	zone := models.NormalZone
+	switch {
+	case rule.reached(avg, danger):
+		zone = models.DangerZone
+	case e.highLoadCounter >= max(rule.streak, 1):
+		zone = models.WarningZone
+	}
+	zone = max(zone, floor)
+
+	if zone < e.zone && now.Sub(e.since) < rule.dwell(e.zone) {
+		zone = e.zone
+	}
+	if zone == e.zone {
+		return avg, zone, nil
+	}
+
+	t := &models.Transition{Metric: e.metric, From: e.zone, To: zone, Value: avg, At: now}
+	e.zone, e.since = zone, now
+	return avg, zone, t
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var noSmoothing = configs.Smoothing{Method: configs.SmoothingSMA, Window: 1}
+
+// zones feeds values to e a second apart and returns their zones.
+func zones(e *evaluator, values ...float64) []models.Zone {
+	start := time.Now()
+
+	var got []models.Zone
+	for i, v := range values {
+		_, zone, _ := e.add(v, models.NormalZone, start.Add(time.Duration(i)*time.Second))
+		got = append(got, zone)
+	}
+	return got
+}
+
+func Test_Evaluator_Streak(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{Warning: 75, Danger: 90, Streak: 3, Smoothing: noSmoothing}))
+
+	assert.Equal(t, []models.Zone{
+		models.NormalZone, models.NormalZone, models.WarningZone, models.DangerZone, models.WarningZone,
+	}, zones(e, 80, 80, 80, 95, 80))
+}
+
+func Test_Evaluator_Decay(t *testing.T) {
+	values := []float64{80, 80, 10, 80, 10, 10, 10, 80}
+
+	tests := []struct {
+		decay string
+		want  []models.Zone
+	}{
+		{configs.DecayStep, []models.Zone{
+			models.NormalZone, models.WarningZone, models.NormalZone, models.WarningZone,
+			models.NormalZone, models.NormalZone, models.NormalZone, models.NormalZone,
+		}},
+		{configs.DecayReset, []models.Zone{
+			models.NormalZone, models.WarningZone, models.NormalZone, models.NormalZone,
+			models.NormalZone, models.NormalZone, models.NormalZone, models.NormalZone,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decay, func(t *testing.T) {
+			e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{Warning: 75, Danger: 90, Streak: 2, Decay: tt.decay, Smoothing: noSmoothing}))
+			assert.Equal(t, tt.want, zones(e, values...))
+		})
+	}
+}
+
+func Test_Evaluator_Falling(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, fallingRule(15, 5))
+	assert.Equal(t, []models.Zone{
+		models.NormalZone, models.WarningZone, models.DangerZone, models.WarningZone, models.NormalZone,
+	}, zones(e, 50, 15, 4, 10, 20))
+
+	e = newEvaluator(models.MetricCPU, fallingRule(0, 5))
+	assert.Equal(t, []models.Zone{models.NormalZone, models.NormalZone, models.DangerZone}, zones(e, 50, 6, 5),
+		"a zero threshold is disabled")
+}
+
+func Test_Evaluator_Transitions(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{Warning: 75, Danger: 90, Streak: 1, Smoothing: noSmoothing}))
+	start := time.Now()
+
+	_, _, tr := e.add(10, models.NormalZone, start)
+	assert.Nil(t, tr, "the evaluator starts in the normal zone")
+
+	_, _, tr = e.add(95, models.NormalZone, start.Add(time.Second))
+	require.NotNil(t, tr)
+	assert.Equal(t, models.Transition{Metric: models.MetricCPU, From: models.NormalZone, To: models.DangerZone, Value: 95, At: start.Add(time.Second)}, *tr)
+
+	_, _, tr = e.add(96, models.NormalZone, start.Add(2*time.Second))
+	assert.Nil(t, tr)
+
+	_, _, tr = e.add(80, models.NormalZone, start.Add(3*time.Second))
+	require.NotNil(t, tr)
+	assert.Equal(t, models.DangerZone, tr.From)
+	assert.Equal(t, models.WarningZone, tr.To)
+}
+
+func Test_Evaluator_Hysteresis(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{
+		Warning: 75, Danger: 90, Streak: 1,
+		WarningExit: 65, DangerExit: 80,
+		Smoothing: noSmoothing,
+	}))
+
+	tests := []struct {
+		value float64
+		want  models.Zone
+	}{
+		{91, models.DangerZone},
+		// oscillating around the danger threshold keeps the danger zone
+		{89, models.DangerZone},
+		{85, models.DangerZone},
+		{79, models.WarningZone},
+		// the high load counter decays while below the warning exit
+		{60, models.WarningZone},
+		{60, models.WarningZone},
+		{60, models.WarningZone},
+		{60, models.NormalZone},
+		{76, models.WarningZone},
+		// staying above the warning exit keeps the warning zone
+		{70, models.WarningZone},
+		{60, models.WarningZone},
+		{60, models.NormalZone},
+		// the warning zone is entered at the entry threshold only
+		{70, models.NormalZone},
+	}
+
+	now := time.Now()
+	for i, tt := range tests {
+		_, zone, _ := e.add(tt.value, models.NormalZone, now.Add(time.Duration(i)*time.Second))
+		assert.Equal(t, tt.want, zone, "sample %d: %v", i, tt.value)
+	}
+}
+
+func Test_Evaluator_Dwell(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{
+		Warning: 75, Danger: 90, Streak: 1,
+		DangerDwell: time.Minute,
+		Smoothing:   noSmoothing,
+	}))
+	start := time.Now()
+
+	_, zone, _ := e.add(95, models.NormalZone, start)
+	assert.Equal(t, models.DangerZone, zone)
+
+	_, zone, _ = e.add(10, models.NormalZone, start.Add(30*time.Second))
+	assert.Equal(t, models.DangerZone, zone, "the danger zone is kept for the dwell time")
+
+	_, zone, _ = e.add(10, models.NormalZone, start.Add(time.Minute))
+	assert.Equal(t, models.NormalZone, zone)
+}
+
+func Test_Evaluator_Floor(t *testing.T) {
+	e := newEvaluator(models.MetricCPU, newZoneRule(configs.Threshold{
+		Warning: 75, Danger: 90, Streak: 1, Decay: configs.DecayReset,
+		WarningExit: 65,
+		Smoothing:   noSmoothing,
+	}))
+	start := time.Now()
+
+	_, zone, tr := e.add(10, models.WarningZone, start)
+	assert.Equal(t, models.WarningZone, zone, "the zone is not better than the floor")
+	require.NotNil(t, tr)
+	assert.Equal(t, models.Transition{Metric: models.MetricCPU, From: models.NormalZone, To: models.WarningZone, Value: 10, At: start}, *tr)
+
+	_, zone, _ = e.add(95, models.WarningZone, start.Add(time.Second))
+	assert.Equal(t, models.DangerZone, zone, "a worse zone of the value is entered")
+
+	_, zone, _ = e.add(70, models.NormalZone, start.Add(2*time.Second))
+	assert.Equal(t, models.WarningZone, zone, "the zone of the floor is left at its exit threshold")
+
+	_, zone, _ = e.add(60, models.NormalZone, start.Add(3*time.Second))
+	assert.Equal(t, models.NormalZone, zone)
+}
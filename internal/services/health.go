@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"health-checker/internal/models"
+	"sort"
+	"time"
+)
+
+// Liveness returns an error naming every collector whose goroutine has
+// stopped because of a panic.
+func (m *Monitor) Liveness() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, name := range m.collectorNames() {
+		if err := m.states[name].err; err != nil {
+			errs = append(errs, fmt.Errorf("collector %s stopped: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Readiness returns an error describing every reason the service should not
+// receive traffic at now: a collector without a sample in the last
+// ReadyIntervals intervals or a metric in the danger zone.
+func (m *Monitor) Readiness(now time.Time) error {
+	errs := []error{m.Liveness()}
+
+	m.mu.Lock()
+	for _, name := range m.collectorNames() {
+		state := m.states[name]
+		maxAge := state.interval * time.Duration(m.cfg.ReadyIntervals)
+
+		switch {
+		case state.err != nil:
+		case state.lastErr != nil:
+			errs = append(errs, fmt.Errorf("collector %s is failing: %w", name, state.lastErr))
+		case state.lastSample.IsZero():
+			errs = append(errs, fmt.Errorf("collector %s has no samples yet", name))
+		case now.Sub(state.lastSample) > maxAge:
+			errs = append(errs, fmt.Errorf("collector %s: last sample is older than %s", name, maxAge))
+		}
+	}
+	m.mu.Unlock()
+
+	for _, u := range m.Snapshot().Metrics {
+		if u.LoadZone == models.DangerZone {
+			errs = append(errs, fmt.Errorf("metric %s is in the danger zone", u.Metric))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Monitor) collectorNames() []string {
+	names := make([]string, 0, len(m.states))
+	for name := range m.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
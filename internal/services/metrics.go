@@ -0,0 +1,148 @@
+package services
+
+import (
+	"health-checker/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "health_checker"
+
+// rawMetric exports a raw value of the devices of a metric.
+type rawMetric struct {
+	raw  string
+	desc *prometheus.Desc
+}
+
+func newRawMetric(raw, name, help, label string) rawMetric {
+	return rawMetric{
+		raw:  raw,
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, []string{label}, nil),
+	}
+}
+
+var (
+	utilizationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "utilization"),
+		"Утилизация системы, для disk_free -- свободное место на самом заполненном томе, %",
+		[]string{"metric"}, nil)
+
+	deviceUtilizationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "device_utilization"),
+		"Утилизация ядра, сетевого интерфейса, диска или свободное место тома, %",
+		[]string{"metric", "device"}, nil)
+
+	zoneDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "zone"),
+		"Зона метрики: 0 pending, 1 normal, 2 unknown, 3 warning, 4 stale, 5 danger",
+		[]string{"metric"}, nil)
+
+	deviceZoneDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "device_zone"),
+		"Зона устройства, значения как у health_checker_zone",
+		[]string{"metric", "device"}, nil)
+
+	sampleTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_sample_timestamp_seconds"),
+		"Время последнего значения метрики",
+		[]string{"metric"}, nil)
+
+	// rawMetrics are the raw values exported per device by metric.
+	rawMetrics = map[string][]rawMetric{
+		models.MetricDisk: {
+			newRawMetric("read_bytes_per_second", "disk_read_bytes_per_second", "Скорость чтения с диска, байт/с", "device"),
+			newRawMetric("write_bytes_per_second", "disk_write_bytes_per_second", "Скорость записи на диск, байт/с", "device"),
+			newRawMetric("reads_per_second", "disk_reads_per_second", "Количество операций чтения с диска в секунду", "device"),
+			newRawMetric("writes_per_second", "disk_writes_per_second", "Количество операций записи на диск в секунду", "device"),
+			newRawMetric("queue_length", "disk_queue_length", "Средняя длина очереди запросов к диску", "device"),
+		},
+		models.MetricDiskFree: {
+			newRawMetric("free_bytes", "disk_free_bytes", "Свободное место на диске, байт", "volume"),
+			newRawMetric("size_bytes", "disk_size_bytes", "Размер диска, байт", "volume"),
+		},
+		models.MetricNet: {
+			newRawMetric("rx_bytes_per_second", "network_receive_bytes_per_second", "Скорость приёма сетевого интерфейса, байт/с", "interface"),
+			newRawMetric("tx_bytes_per_second", "network_transmit_bytes_per_second", "Скорость передачи сетевого интерфейса, байт/с", "interface"),
+		},
+	}
+)
+
+// exporter exports the state of a Monitor to Prometheus. The utilization
+// and zones are read from a snapshot on every scrape, so devices that are
+// no longer reported disappear from the output.
+type exporter struct {
+	monitor *Monitor
+
+	registry           *prometheus.Registry
+	collectorErrors    *prometheus.CounterVec
+	collectionDuration *prometheus.HistogramVec
+}
+
+func newExporter(m *Monitor) *exporter {
+	e := &exporter{
+		monitor:  m,
+		registry: prometheus.NewRegistry(),
+		collectorErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "collector_errors_total",
+				Help:      "Количество ошибок получения данных сборщиком",
+			}, []string{"collector"}),
+		collectionDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "collection_duration_seconds",
+				Help:      "Длительность опроса сборщика",
+				Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+			}, []string{"collector"}),
+	}
+
+	e.registry.MustRegister(
+		e,
+		e.collectorErrors,
+		e.collectionDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return e
+}
+
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- utilizationDesc
+	ch <- deviceUtilizationDesc
+	ch <- zoneDesc
+	ch <- deviceZoneDesc
+	ch <- sampleTimeDesc
+	for _, raws := range rawMetrics {
+		for _, r := range raws {
+			ch <- r.desc
+		}
+	}
+}
+
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	snap := e.monitor.Snapshot()
+
+	for _, u := range snap.Metrics {
+		ch <- prometheus.MustNewConstMetric(zoneDesc, prometheus.GaugeValue, float64(u.ZoneAt(snap.TakenAt)), u.Metric)
+		if u.UpdatedAt.IsZero() {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(utilizationDesc, prometheus.GaugeValue, u.Value, u.Metric)
+		ch <- prometheus.MustNewConstMetric(sampleTimeDesc, prometheus.GaugeValue, float64(u.UpdatedAt.UnixNano())/1e9, u.Metric)
+
+		for _, d := range u.Devices {
+			ch <- prometheus.MustNewConstMetric(deviceUtilizationDesc, prometheus.GaugeValue, d.Value, u.Metric, d.Device)
+			ch <- prometheus.MustNewConstMetric(deviceZoneDesc, prometheus.GaugeValue, float64(d.ZoneAt(snap.TakenAt)), u.Metric, d.Device)
+
+			for _, r := range rawMetrics[u.Metric] {
+				if v, ok := d.Raw[r.raw]; ok {
+					ch <- prometheus.MustNewConstMetric(r.desc, prometheus.GaugeValue, v, d.Device)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue returns the value of the series of name with the given
+// labels gathered from the registry of m.
+func metricValue(t *testing.T, m *Monitor, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+
+	families, err := m.Registry().Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+
+	series:
+		for _, metric := range f.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if labels[l.GetName()] != l.GetValue() {
+					continue series
+				}
+			}
+			if metric.GetCounter() != nil {
+				return metric.GetCounter().GetValue(), true
+			}
+			return metric.GetGauge().GetValue(), true
+		}
+	}
+
+	return 0, false
+}
+
+func Test_Exporter_Collect(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.Disk.Streak = 1
+	m := NewMonitor(cfg)
+
+	m.record(models.Reading{Metric: models.MetricDisk, Device: "sda", Value: 85,
+		Raw: map[string]float64{"queue_length": 12}})
+	m.record(models.Reading{Metric: models.MetricDisk, Value: 85})
+
+	v, ok := metricValue(t, m, "health_checker_utilization", map[string]string{"metric": "disk"})
+	require.True(t, ok)
+	assert.Equal(t, 85.0, v)
+
+	v, _ = metricValue(t, m, "health_checker_zone", map[string]string{"metric": "disk"})
+	assert.Equal(t, float64(models.WarningZone), v)
+
+	v, _ = metricValue(t, m, "health_checker_zone", map[string]string{"metric": "cpu"})
+	assert.Equal(t, float64(models.PendingZone), v)
+	_, ok = metricValue(t, m, "health_checker_utilization", map[string]string{"metric": "cpu"})
+	assert.False(t, ok, "metrics without samples have no value")
+
+	v, _ = metricValue(t, m, "health_checker_device_utilization", map[string]string{"metric": "disk", "device": "sda"})
+	assert.Equal(t, 85.0, v)
+
+	v, _ = metricValue(t, m, "health_checker_disk_queue_length", map[string]string{"device": "sda"})
+	assert.Equal(t, 12.0, v)
+}
+
+func Test_Exporter_VolumesAddedAndRemoved(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+
+	sample := func(volumes ...string) {
+		for _, v := range volumes {
+			m.record(models.Reading{Metric: models.MetricDiskFree, Device: v, Value: 50,
+				Raw: map[string]float64{"free_bytes": 50, "size_bytes": 100}})
+		}
+		m.record(models.Reading{Metric: models.MetricDiskFree, Value: 50})
+	}
+
+	sample("E:", "F:")
+	v, _ := metricValue(t, m, "health_checker_disk_free_bytes", map[string]string{"volume": "E:"})
+	assert.Equal(t, 50.0, v)
+	v, _ = metricValue(t, m, "health_checker_disk_size_bytes", map[string]string{"volume": "F:"})
+	assert.Equal(t, 100.0, v)
+
+	// F: is unmounted and its series are removed
+	sample("E:")
+	_, ok := metricValue(t, m, "health_checker_disk_free_bytes", map[string]string{"volume": "F:"})
+	assert.False(t, ok)
+	_, ok = metricValue(t, m, "health_checker_disk_free_bytes", map[string]string{"volume": "E:"})
+	assert.True(t, ok)
+}
+
+func Test_Exporter_SeveralMonitors(t *testing.T) {
+	a := NewMonitor(configs.DefaultChecker())
+	b := NewMonitor(configs.DefaultChecker())
+
+	a.record(models.Reading{Metric: models.MetricCPU, Value: 10})
+
+	_, ok := metricValue(t, a, "health_checker_utilization", map[string]string{"metric": "cpu"})
+	assert.True(t, ok)
+	_, ok = metricValue(t, b, "health_checker_utilization", map[string]string{"metric": "cpu"})
+	assert.False(t, ok)
+}
+
+func Test_Exporter_Lint(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+	m.record(models.Reading{Metric: models.MetricCPU, Device: "0", Value: 10})
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 10})
+
+	problems, err := testutil.GatherAndLint(m.Registry())
+	require.NoError(t, err)
+	for _, p := range problems {
+		assert.False(t, strings.HasPrefix(p.Metric, namespace), "%s: %s", p.Metric, p.Text)
+	}
+}
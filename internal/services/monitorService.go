@@ -2,493 +2,503 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"health-checker/internal/collectors"
 	"health-checker/internal/configs"
 	"health-checker/internal/models"
+	"health-checker/internal/storage"
 	"log/slog"
+	"maps"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/yusufpapurcu/wmi"
 )
 
-const (
-	NormalZone  = "normal"
-	WarningZone = "warning"
-	DangerZone  = "danger"
-)
-
-type proc struct {
-	PercentProcessorTime uint64
-	TimeStamp_Sys100NS   uint64
-}
-
-type mem struct {
-	AvailableMBytes uint64
+// deviceRule evaluates a value of the devices of a metric.
+type deviceRule struct {
+	zoneRule
+	// raw is the raw value evaluated, the value of the reading when empty.
+	raw string
 }
 
-type net struct {
-	CurrentBandwidth uint32
-	BytesTotalPerSec uint64
+// deviceEvaluator evaluates a value of a device according to a deviceRule.
+type deviceEvaluator struct {
+	*evaluator
+	raw string
 }
 
-type disk struct {
-	PercentDiskTime uint64
+type metric struct {
+	// utilization is guarded by Monitor.utilMu.
+	utilization models.Utilization
+	// evaluator evaluates the host-wide values bounded by the zones of the
+	// devices, its transitions are the transitions of the metric.
+	evaluator *evaluator
+
+	// deviceRules returns the rules evaluating a device of the metric, e.g.
+	// a CPU core, the zone of the device is the worst of them. Devices are
+	// reported in the normal zone when there are none.
+	deviceRules func(device string) []deviceRule
+	devices     map[string][]deviceEvaluator
+	// deviceUtilization is guarded by Monitor.utilMu.
+	deviceUtilization map[string]models.Utilization
+	// reported are the devices reported since the last host-wide reading,
+	// the others have disappeared and are dropped by the next one.
+	reported map[string]bool
 }
 
-type networkName struct {
-	InterfaceDescription string
-}
-
-type diskFreeSpace struct {
-	FreeSpace uint64
-	Size      uint64
-	Name      string
+// collectorState tracks the health of a collector goroutine.
+type collectorState struct {
+	interval   time.Duration
+	lastSample time.Time
+	// lastErr is the error of the last sample, nil once the collector recovers.
+	lastErr error
+	// err is set once the goroutine has stopped because of a panic.
+	err error
 }
 
 type Monitor struct {
-	cpuUtilization  models.Utilization
-	ramUtilization  models.Utilization
-	netUtilization  models.Utilization
-	diskUtilization models.Utilization
+	cfg        configs.Checker
+	collectors []collectors.Collector
+	metrics    map[string]*metric
+
+	mu          sync.Mutex
+	states      map[string]*collectorState
+	subscribers []chan models.Transition
+
+	// utilMu guards the utilization of every metric, so a snapshot is
+	// consistent across metrics.
+	utilMu sync.RWMutex
+
+	history  *models.History
+	exporter *exporter
+	// store keeps the raw host-wide samples of the metrics.
+	store *storage.Store
 }
 
-var (
-	cpu = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "cpu_utilization",
-			Help: "Утилизация процессора",
-		})
+// metricOrder is the order of metrics in a snapshot.
+var metricOrder = []string{models.MetricCPU, models.MetricRAM, models.MetricNet, models.MetricDisk, models.MetricDiskFree}
+
+// NewMonitor creates a Monitor driven by the given collectors.
+func NewMonitor(cfg configs.Checker, c ...collectors.Collector) *Monitor {
+	m := &Monitor{
+		cfg:        cfg,
+		collectors: c,
+		states:     make(map[string]*collectorState, len(c)),
+		history:    models.NewHistory(cfg.HistorySize),
+		store:      storage.NewMemory(cfg.StorageRawSamples),
+	}
+	m.exporter = newExporter(m)
 
-	memory = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "memory_utilization",
-			Help: "Утилизация оперативной памяти",
-		})
+	for _, c := range c {
+		interval := cfg.Interval
+		if s, ok := c.(collectors.Scheduled); ok && s.Interval() > 0 {
+			interval = s.Interval()
+		}
+		m.states[c.Name()] = &collectorState{interval: interval}
+	}
 
-	diskIO = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "disk_utilization",
-			Help: "Утилизация I/O диска",
-		})
+	m.metrics = map[string]*metric{
+		models.MetricCPU:  newMetric(models.MetricCPU, newZoneRule(cfg.CPU)),
+		models.MetricRAM:  newMetric(models.MetricRAM, newZoneRule(cfg.RAM)),
+		models.MetricNet:  newMetric(models.MetricNet, newZoneRule(cfg.Net)),
+		models.MetricDisk: newMetric(models.MetricDisk, newZoneRule(cfg.Disk)),
+		// the zone of the free space is evaluated per volume
+		models.MetricDiskFree: newMetric(models.MetricDiskFree, fallingRule(0, 0)),
+	}
 
-	network = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "network_utilization",
-			Help: "Утилизация сети",
-		})
-)
+	if cfg.CPUCoreWarning > 0 {
+		// a core is only reported in the warning zone, the danger zone of
+		// the processor is left to the host-wide utilization
+		core := newZoneRule(cfg.CPU)
+		core.warning, core.warningExit = cfg.CPUCoreWarning, cfg.CPUCoreWarning
+		core.danger, core.dangerExit = math.Inf(1), math.Inf(1)
+		m.metrics[models.MetricCPU].deviceRules = sameRules(deviceRule{zoneRule: core})
+	}
 
-func NewMonitor() *Monitor {
-	return &Monitor{}
-}
+	// every interface is evaluated like the network, so the busiest one
+	// drives the zone of the network
+	m.metrics[models.MetricNet].deviceRules = sameRules(deviceRule{zoneRule: newZoneRule(cfg.Net)})
 
-func (m *Monitor) Start(ctx context.Context, cfg configs.Checker) {
-	go func() {
-		slog.Debug("monitoring of the processor load is started")
+	// a single saturated disk is not hidden by the idle ones
+	m.metrics[models.MetricDisk].deviceRules = sameRules(deviceRule{zoneRule: newZoneRule(cfg.Disk)})
 
-		err := m.getCPUUtilization(ctx, cfg.Interval)
-		if err != nil {
-			slog.Error("processor data retrieval error", "error", err)
+	// a volume is in a zone when either its free space in percent or in
+	// bytes drops to the boundary
+	m.metrics[models.MetricDiskFree].deviceRules = func(volume string) []deviceRule {
+		t := cfg.FreeSpace(volume)
+		return []deviceRule{
+			{zoneRule: fallingRule(t.WarningPercent, t.DangerPercent)},
+			{zoneRule: fallingRule(float64(t.WarningBytes), float64(t.DangerBytes)), raw: "free_bytes"},
 		}
-	}()
+	}
 
-	go func() {
-		slog.Debug("RAM load monitoring started")
+	for name, mt := range m.metrics {
+		mt.utilization.Metric = name
+		mt.utilization.MaxAge = m.maxAge(name)
+	}
 
-		err := m.getRAMUtilization(ctx, cfg.Interval)
-		if err != nil {
-			slog.Error("RAM data retrieval error", "error", err)
-		}
-	}()
+	return m
+}
 
-	go func() {
-		slog.Debug("network load monitoring started")
+// sameRules returns deviceRules evaluating every device by rules.
+func sameRules(rules ...deviceRule) func(string) []deviceRule {
+	return func(string) []deviceRule {
+		return rules
+	}
+}
 
-		err := m.getNetUtilization(ctx, cfg.Interval)
-		if err != nil {
-			slog.Error("network data retrieval error", "error", err)
-		}
-	}()
+func newMetric(name string, rule zoneRule) *metric {
+	return &metric{
+		evaluator:         newEvaluator(name, rule),
+		devices:           make(map[string][]deviceEvaluator),
+		deviceUtilization: make(map[string]models.Utilization),
+		reported:          make(map[string]bool),
+	}
+}
 
-	go func() {
-		slog.Debug("disk load monitoring started")
+func (m *Monitor) Start(ctx context.Context) {
+	for _, c := range m.collectors {
+		go func(c collectors.Collector) {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("collector panicked", "collector", c.Name(), "panic", r)
+					m.stopped(c.Name(), fmt.Errorf("panic: %v", r))
+				}
+			}()
+
+			slog.Debug("monitoring started", "collector", c.Name())
+			m.run(ctx, c)
+		}(c)
+	}
+}
 
-		err := m.getDiskUtilization(ctx, cfg.Interval)
-		if err != nil {
-			slog.Error("disk data retrieval error", "error", err)
-		}
-	}()
+// run polls c every interval. A failing collector is retried with an
+// exponential backoff and its metric is reported in the unknown zone until
+// a sample succeeds again.
+func (m *Monitor) run(ctx context.Context, c collectors.Collector) {
+	interval := m.interval(c.Name())
+	failures := 0
 
-	go func() {
-		slog.Debug("disk free space monitoring started")
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
-		err := m.GetDiskFreeSpace(ctx, cfg.Interval)
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			slog.Debug("monitoring stopped", "collector", c.Name())
+			return
+		}
+
+		started := time.Now()
+		readings, err := c.Sample(ctx)
+		m.exporter.collectionDuration.WithLabelValues(c.Name()).Observe(time.Since(started).Seconds())
 		if err != nil {
-			slog.Error("disk free space data retrieval error", "error", err)
+			failures++
+			wait := backoff(interval, m.cfg.RetryMaxBackoff, failures)
+			slog.Error("data retrieval error", "collector", c.Name(), "error", err, "retry_in", wait)
+
+			m.exporter.collectorErrors.WithLabelValues(c.Name()).Inc()
+			m.failed(c.Name(), err)
+			timer.Reset(wait)
+			continue
 		}
-	}()
-}
 
-func (m *Monitor) getCPUUtilization(ctx context.Context, interval time.Duration) error {
-	var (
-		startPoint         []proc
-		endPoint           []proc
-		startPointProcTime uint64
-		startPointTS       uint64
-		endPointProcTime   uint64
-		endPointTS         uint64
-		cpuUtil            float64
-		highLoadCounter    int
-		cpuUtilFormatted   string
-		err                error
-	)
-
-	const query = "SELECT PercentProcessorTime, TimeStamp_Sys100NS FROM Win32_PerfRawData_PerfOS_Processor WHERE Name = '_Total'"
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+		if failures > 0 {
+			slog.Info("collector recovered", "collector", c.Name(), "failures", failures)
+			failures = 0
+		}
 
-	for {
-		select {
-		case <-ticker.C:
-			err = wmi.Query(query, &startPoint)
-			if err != nil {
-				return err
-			}
-
-			if len(startPoint) == 0 {
-				return errors.New("no processor data")
-			}
-
-			startPointProcTime = startPoint[0].PercentProcessorTime
-			startPointTS = startPoint[0].TimeStamp_Sys100NS
-
-			time.Sleep(interval)
-
-			err = wmi.Query(query, &endPoint)
-			if err != nil {
-				return err
-			}
-
-			if len(endPoint) == 0 {
-				return errors.New("no processor data")
-			}
-
-			endPointProcTime = endPoint[0].PercentProcessorTime
-			endPointTS = endPoint[0].TimeStamp_Sys100NS
-
-			/*
-				CPU utilization calculation mechanism
-				is based on https://learn.microsoft.com/en-us/windows/win32/wmisdk/monitoring-performance-data#using-raw-performance-data-classes
-			*/
-			cpuUtil = (1.0 - float64(endPointProcTime-startPointProcTime)/float64(endPointTS-startPointTS)) * 100
-			if cpuUtil >= 75 {
-				highLoadCounter++
-			} else if highLoadCounter > 0 {
-				highLoadCounter--
-			}
-			cpuUtilFormatted = fmt.Sprintf("%.*f", 2, cpuUtil)
-
-			m.cpuUtilization.Lock()
-
-			if cpuUtil >= 90 {
-				m.cpuUtilization.LoadZone = DangerZone
-			} else if highLoadCounter >= 10 {
-				m.cpuUtilization.LoadZone = WarningZone
-			} else {
-				m.cpuUtilization.LoadZone = NormalZone
-			}
-
-			m.cpuUtilization.Value = cpuUtilFormatted
-			m.cpuUtilization.Unlock()
-			cpu.Set(cpuUtil)
-
-			slog.Debug("", "CPU load", cpuUtilFormatted)
-		case <-ctx.Done():
-			slog.Debug("CPU load monitoring is stopped")
-			return nil
+		for _, r := range readings {
+			m.record(r)
+		}
+		if len(readings) > 0 {
+			m.sampled(c.Name(), time.Now())
 		}
+
+		timer.Reset(interval)
 	}
 }
 
-func (m *Monitor) getRAMUtilization(ctx context.Context, interval time.Duration) error {
-	type memInfo struct {
-		Capacity uint64
+// minBackoff is the shortest delay between attempts, so a zero interval does
+// not retry in a busy loop.
+const minBackoff = 10 * time.Millisecond
+
+// backoff returns the delay before the next attempt after the given number of
+// consecutive failures: the interval doubled for every failure but the first,
+// capped at maxBackoff and at least minBackoff.
+func backoff(interval, maxBackoff time.Duration, failures int) time.Duration {
+	interval = max(interval, minBackoff)
+	if maxBackoff < interval {
+		return interval
 	}
 
-	var (
-		memI            []memInfo
-		capacity        uint64
-		availableMemory float64
-		highLoadCounter int
-		avg             float64
-		avgFormatted    string
-	)
-
-	query := "SELECT capacity FROM Win32_PhysicalMemory"
-	err := wmi.Query(query, &memI)
-	if err != nil {
-		return err
+	wait := interval
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
 	}
-	if len(memI) == 0 {
-		return errors.New("no memory data")
+	return min(wait, maxBackoff)
+}
+
+// maxAge returns the configured max age of metric samples, by default
+// ReadyIntervals intervals of the collector reporting the metric. A collector
+// polled on its own interval, e.g. the free space, gets at least the default,
+// so it is not reported stale between its samples.
+func (m *Monitor) maxAge(metric string) time.Duration {
+	interval := m.cfg.Interval
+	if state, ok := m.states[metric]; ok {
+		interval = state.interval
 	}
 
-	for _, v := range memI {
-		capacity += v.Capacity
+	if m.cfg.MaxAge > 0 && interval == m.cfg.Interval {
+		return m.cfg.MaxAge
 	}
-	capacity = capacity / 1024 / 1024
-	slog.Debug("", "memory capacity", capacity)
+	return max(m.cfg.MaxAge, interval*time.Duration(m.cfg.ReadyIntervals))
+}
 
-	query = "SELECT AvailableMBytes FROM Win32_PerfFormattedData_PerfOS_Memory"
-	var memoryPoint []mem
+func (m *Monitor) interval(collector string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	return m.states[collector].interval
+}
 
-	buf := models.NewRingBuffer(5)
+func (m *Monitor) sampled(collector string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	for {
-		select {
-		case <-ticker.C:
-			err = wmi.Query(query, &memoryPoint)
-			if err != nil {
-				return err
-			}
-			if len(memoryPoint) == 0 {
-				return errors.New("no memory data")
-			}
-
-			availableMemory = float64(memoryPoint[0].AvailableMBytes) / float64(capacity) * 100
-			buf.Add(availableMemory)
-
-			avg = buf.GetAverage()
-			if avg <= 25 {
-				highLoadCounter++
-			} else if highLoadCounter > 0 {
-				highLoadCounter--
-			}
-
-			avgFormatted = fmt.Sprintf("%.*f", 2, avg)
-			slog.Debug("", "available memory in percent", avgFormatted)
-
-			m.ramUtilization.Lock()
-			if avg <= 10 {
-				m.ramUtilization.LoadZone = DangerZone
-			} else if highLoadCounter >= 10 {
-				m.ramUtilization.LoadZone = WarningZone
-			} else {
-				m.ramUtilization.LoadZone = NormalZone
-			}
-			m.ramUtilization.Value = avgFormatted
-			m.ramUtilization.Unlock()
-			memory.Set(avg)
-		case <-ctx.Done():
-			slog.Debug("RAM load monitoring is stopped")
-			return nil
-		}
-	}
+	m.states[collector].lastSample = at
+	m.states[collector].lastErr = nil
 }
 
-func (m *Monitor) getNetUtilization(ctx context.Context, interval time.Duration) error {
-	var (
-		netUtil         float64
-		avg             float64
-		highLoadCounter int
-		netInfo         []net
-		netName         []networkName
-		avgFormatted    string
-		err             error
-	)
-
-	query := "SELECT InterfaceDescription FROM MSFT_NetAdapter WHERE ConnectorPresent=1"
-	err = wmi.QueryNamespace(query, &netName, `root\StandardCimv2`)
-	if err != nil {
-		return err
-	}
-	if len(netName) == 0 {
-		return errors.New("no network data")
+// failed records a failed sample of collector and marks its metric unknown.
+func (m *Monitor) failed(collector string, err error) {
+	m.mu.Lock()
+	m.states[collector].lastErr = err
+	m.mu.Unlock()
+
+	mt, ok := m.metrics[collector]
+	if !ok {
+		return
 	}
 
-	slog.Debug("", "network name", netName[0].InterfaceDescription)
+	m.utilMu.Lock()
+	mt.utilization.LoadZone = models.UnknownZone
+	m.utilMu.Unlock()
+}
 
-	query = "SELECT CurrentBandwidth, BytesTotalPerSec FROM Win32_PerfFormattedData_Tcpip_NetworkInterface where Name = '" + netName[0].InterfaceDescription + "'"
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+func (m *Monitor) stopped(collector string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	buf := models.NewRingBuffer(5)
+	m.states[collector].err = err
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			err = wmi.Query(query, &netInfo)
-			if err != nil {
-				return err
-			}
-			if len(netInfo) == 0 {
-				return errors.New("no network data")
-			}
-
-			netUtil = 8 * float64(netInfo[0].BytesTotalPerSec) / float64(netInfo[0].CurrentBandwidth) * 100
-			buf.Add(netUtil)
-
-			avg = buf.GetAverage()
-			if avg >= 80 {
-				highLoadCounter++
-			} else if highLoadCounter > 0 {
-				highLoadCounter--
-			}
-
-			avgFormatted = fmt.Sprintf("%.*f", 2, avg)
-			slog.Debug("", "network utilization", avgFormatted)
-			m.netUtilization.Lock()
-			if avg >= 90 {
-				m.netUtilization.LoadZone = DangerZone
-			} else if highLoadCounter >= 10 {
-				m.netUtilization.LoadZone = WarningZone
-			} else {
-				m.netUtilization.LoadZone = NormalZone
-			}
-			m.netUtilization.Value = avgFormatted
-			m.netUtilization.Unlock()
-			network.Set(avg)
-		case <-ctx.Done():
-			slog.Debug("network load monitoring is stopped")
-			return nil
+func (m *Monitor) record(r models.Reading) {
+	mt, ok := m.metrics[r.Metric]
+	if !ok {
+		slog.Debug("reading of unknown metric is skipped", "metric", r.Metric)
+		return
+	}
+
+	if r.Device != "" {
+		m.recordDevice(mt, r)
+		return
+	}
+
+	now := time.Now()
+
+	m.utilMu.Lock()
+	devices := models.NormalZone
+	for device, u := range mt.deviceUtilization {
+		if !mt.reported[device] {
+			m.dropDevice(mt, device)
+			continue
 		}
+		devices = max(devices, u.LoadZone)
+	}
+	clear(mt.reported)
+	avg, zone, transition := mt.evaluator.add(r.Value, devices, now)
+	mt.utilization.LoadZone = zone
+	mt.utilization.Value = avg
+	mt.utilization.Unit = r.Unit
+	mt.utilization.Raw = r.Raw
+	mt.utilization.UpdatedAt = now
+	m.utilMu.Unlock()
+	slog.Debug("", r.Metric, fmt.Sprintf("%.*f", 2, avg))
+
+	if err := m.store.Add(r.Metric, now, r.Value); err != nil {
+		slog.Error("sample is not stored", "metric", r.Metric, "error", err)
 	}
 
+	if transition != nil {
+		m.transition(*transition)
+	}
 }
 
-func (m *Monitor) getDiskUtilization(ctx context.Context, interval time.Duration) error {
-	var (
-		diskInfo        []disk
-		highLoadCounter int
-		diskUtil        float64
-		avg             float64
-		avgFormatted    string
-		err             error
-	)
-	query := "SELECT PercentDiskTime FROM Win32_PerfFormattedData_PerfDisk_PhysicalDisk WHERE Name = '_Total'"
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	buf := models.NewRingBuffer(5)
-	for {
+// transition logs t, appends it to the history and sends it to the
+// subscribers. Transitions to a worse zone are logged as warnings.
+func (m *Monitor) transition(t models.Transition) {
+	level := slog.LevelInfo
+	if t.To > t.From {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "zone changed",
+		"metric", t.Metric, "from", t.From, "to", t.To, "value", fmt.Sprintf("%.*f", 2, t.Value))
+
+	m.history.Add(t)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ch := range m.subscribers {
 		select {
-		case <-ticker.C:
-			err = wmi.Query(query, &diskInfo)
-			if err != nil {
-				return err
-			}
-			if len(diskInfo) == 0 {
-				return errors.New("no disk data")
-			}
-
-			diskUtil = float64(diskInfo[0].PercentDiskTime)
-			buf.Add(diskUtil)
-
-			avg = buf.GetAverage()
-			if avg >= 80 {
-				highLoadCounter++
-			} else if highLoadCounter > 0 {
-				highLoadCounter--
-			}
-
-			avgFormatted = fmt.Sprintf("%.*f", 2, avg)
-			slog.Debug("", "disk utilization", avgFormatted)
-			m.diskUtilization.Lock()
-			if avg >= 90 {
-				m.diskUtilization.LoadZone = DangerZone
-			} else if highLoadCounter >= 10 {
-				m.diskUtilization.LoadZone = WarningZone
-			} else {
-				m.diskUtilization.LoadZone = NormalZone
-			}
-			m.diskUtilization.Value = avgFormatted
-			m.diskUtilization.Unlock()
-			diskIO.Set(avg)
-		case <-ctx.Done():
-			slog.Debug("disk load monitoring is stopped")
-			return nil
+		case ch <- t:
+		default:
+			slog.Warn("zone transition is dropped, the subscriber is busy", "metric", t.Metric)
 		}
 	}
 }
 
-func (m *Monitor) GetDiskFreeSpace(ctx context.Context, interval time.Duration) error {
-	var (
-		diskInfo         []diskFreeSpace
-		freeSpaceRounded string
-		freeSpace        float64
-		err              error
-	)
+// Subscribe returns a channel receiving the zone transitions of every
+// metric. A transition is dropped when the buffer of the channel is full,
+// so the collectors are never blocked by a slow subscriber.
+func (m *Monitor) Subscribe(buffer int) <-chan models.Transition {
+	ch := make(chan models.Transition, buffer)
 
-	diskMetrics := make(map[string]prometheus.Gauge)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	query := "SELECT FreeSpace, Size, Name FROM Win32_LogicalDisk"
-	ticker := time.NewTicker(time.Hour)
-	defer ticker.Stop()
+	m.subscribers = append(m.subscribers, ch)
+	return ch
+}
 
-	err = wmi.Query(query, &diskInfo)
-	if err != nil {
-		return err
-	}
-	if len(diskInfo) == 0 {
-		return errors.New("no disk data")
+// History returns the last zone transitions from the oldest to the newest.
+func (m *Monitor) History() []models.Transition {
+	return m.history.Transitions()
+}
+
+// recordDevice evaluates a per-device reading of mt. Device zones are taken
+// into account by the next host-wide reading of the metric, so collectors
+// report devices before the host-wide value.
+func (m *Monitor) recordDevice(mt *metric, r models.Reading) {
+	evaluators, ok := mt.devices[r.Device]
+	if !ok && mt.deviceRules != nil {
+		for _, rule := range mt.deviceRules(r.Device) {
+			evaluators = append(evaluators, deviceEvaluator{evaluator: newEvaluator(r.Metric, rule.zoneRule), raw: rule.raw})
+		}
+		mt.devices[r.Device] = evaluators
 	}
 
-	for _, v := range diskInfo {
-		freeSpace = float64(v.FreeSpace) / 1024 / 1024 / 1024
-		freeSpaceRounded = fmt.Sprintf("%.2f", freeSpace)
-		slog.Debug("", "disk free space in GB", freeSpaceRounded, "disk size", v.Size, "disk name", v.Name)
+	// the value of the device is the smoothed value of its first rule
+	value, zone := r.Value, models.NormalZone
+	now := time.Now()
+	for i, e := range evaluators {
+		v, ok := r.Value, true
+		if e.raw != "" {
+			v, ok = r.Raw[e.raw]
+		}
+		if !ok {
+			continue
+		}
 
-		diskMetrics[v.Name] = promauto.NewGauge(
-			prometheus.GaugeOpts{
-				Name: "disk_info_" + v.Name,
-				Help: "Свободное место на диске " + v.Name,
-			})
+		avg, z, _ := e.add(v, models.NormalZone, now)
+		if i == 0 {
+			value = avg
+		}
+		zone = max(zone, z)
 	}
 
-	for {
-		select {
-		case <-ticker.C:
-			err = wmi.Query(query, &diskInfo)
-			if err != nil {
-				return err
-			}
-			if len(diskInfo) == 0 {
-				return errors.New("no disk data")
-			}
-
-			for _, v := range diskInfo {
-				freeSpace = float64(v.FreeSpace) / 1024 / 1024 / 1024
-				freeSpaceRounded = fmt.Sprintf("%.2f", freeSpace)
-				slog.Debug("", "disk free space in GB", freeSpaceRounded, "disk size", v.Size, "disk name", v.Name)
-
-				diskMetrics[v.Name].Set(freeSpace)
-			}
-		case <-ctx.Done():
-			slog.Debug("disk free space monitoring is stopped")
-			return nil
-		}
+	m.utilMu.Lock()
+	mt.reported[r.Device] = true
+	mt.deviceUtilization[r.Device] = models.Utilization{
+		Metric:    r.Metric,
+		Device:    r.Device,
+		Value:     value,
+		Unit:      r.Unit,
+		LoadZone:  zone,
+		Raw:       r.Raw,
+		UpdatedAt: time.Now(),
+		MaxAge:    mt.utilization.MaxAge,
 	}
+	m.utilMu.Unlock()
+}
+
+// dropDevice forgets a device of mt that is no longer reported. It must be
+// called with utilMu held.
+func (m *Monitor) dropDevice(mt *metric, device string) {
+	slog.Debug("device is no longer reported", "metric", mt.utilization.Metric, "device", device)
+
+	delete(mt.deviceUtilization, device)
+	delete(mt.devices, device)
 }
 
-func (m *Monitor) GetCPUUtilizationValue() *models.Utilization {
-	return &m.cpuUtilization
+// Config returns the configuration the monitor was created with.
+func (m *Monitor) Config() configs.Checker {
+	return m.cfg
 }
 
-func (m *Monitor) GetRAMUtilizationValue() *models.Utilization {
-	return &m.ramUtilization
+// Registry returns the Prometheus registry holding the metrics of the monitor.
+func (m *Monitor) Registry() *prometheus.Registry {
+	return m.exporter.registry
 }
 
-func (m *Monitor) GetNetUtilizationValue() *models.Utilization {
-	return &m.netUtilization
+// SetStore replaces the store of the samples kept in memory by default. It
+// must be called before Start.
+func (m *Monitor) SetStore(s *storage.Store) {
+	m.store = s
 }
 
-func (m *Monitor) GetDiskUtilizationValue() *models.Utilization {
-	return &m.diskUtilization
+// Store returns the store of the raw host-wide samples of the metrics.
+func (m *Monitor) Store() *storage.Store {
+	return m.store
+}
+
+// Threshold returns the configured zone boundaries of metric.
+func (m *Monitor) Threshold(metric string) configs.Threshold {
+	switch metric {
+	case models.MetricCPU:
+		return m.cfg.CPU
+	case models.MetricRAM:
+		return m.cfg.RAM
+	case models.MetricNet:
+		return m.cfg.Net
+	case models.MetricDisk:
+		return m.cfg.Disk
+	case models.MetricDiskFree:
+		return configs.Threshold{Warning: m.cfg.DiskFree.WarningPercent, Danger: m.cfg.DiskFree.DangerPercent}
+	}
+	return configs.Threshold{}
+}
+
+// Snapshot returns a copy of the utilization of every metric taken at once.
+func (m *Monitor) Snapshot() models.Snapshot {
+	m.utilMu.RLock()
+	defer m.utilMu.RUnlock()
+
+	snap := models.Snapshot{
+		TakenAt: time.Now(),
+		Metrics: make([]models.Utilization, 0, len(metricOrder)),
+	}
+	for _, name := range metricOrder {
+		mt := m.metrics[name]
+		u := mt.utilization
+		u.Raw = maps.Clone(u.Raw)
+
+		u.Devices = make([]models.Utilization, 0, len(mt.deviceUtilization))
+		for _, d := range mt.deviceUtilization {
+			d.Raw = maps.Clone(d.Raw)
+			u.Devices = append(u.Devices, d)
+		}
+		sort.Slice(u.Devices, func(i, j int) bool {
+			return models.LessDevice(u.Devices[i].Device, u.Devices[j].Device)
+		})
+
+		snap.Metrics = append(snap.Metrics, u)
+	}
+
+	return snap
 }
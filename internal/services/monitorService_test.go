@@ -2,31 +2,501 @@ package services
 
 import (
 	"context"
+	"errors"
 	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+type fakeCollector struct {
+	metric string
+	value  float64
+}
+
+func (f *fakeCollector) Name() string {
+	return f.metric
+}
+
+func (f *fakeCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	return []models.Reading{{Metric: f.metric, Value: f.value, Unit: models.UnitPercent}}, nil
+}
+
 func Test_Monitor_Start(t *testing.T) {
-	monitor := NewMonitor()
+	cfg := configs.DefaultChecker()
+	cfg.Interval = time.Microsecond
+	monitor := NewMonitor(cfg,
+		&fakeCollector{metric: models.MetricCPU, value: 10},
+		&fakeCollector{metric: models.MetricRAM, value: 60},
+		&fakeCollector{metric: models.MetricNet, value: 5},
+		&fakeCollector{metric: models.MetricDisk, value: 1},
+		&fakeCollector{metric: models.MetricDiskFree, value: 50},
+	)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	monitor.Start(ctx, configs.Checker{Interval: time.Microsecond})
+	monitor.Start(ctx)
+
+	assert.Eventually(t, func() bool {
+		for _, u := range monitor.Snapshot().Metrics {
+			if u.UpdatedAt.IsZero() {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+
+	snap := monitor.Snapshot()
+	require.Len(t, snap.Metrics, 5)
+	for _, u := range snap.Metrics {
+		assert.Equal(t, models.NormalZone, u.LoadZone, u.Metric)
+		assert.Equal(t, models.UnitPercent, u.Unit, u.Metric)
+		assert.False(t, u.UpdatedAt.IsZero(), u.Metric)
+	}
+
+	cpu, ok := snap.Get(models.MetricCPU)
+	require.True(t, ok)
+	assert.Equal(t, 10.0, cpu.Value)
+}
+
+func Test_Monitor_Record(t *testing.T) {
+	const streak = 10
+
+	tests := []struct {
+		name   string
+		metric string
+		values []float64
+		want   models.Zone
+	}{
+		{"cpu normal", models.MetricCPU, []float64{10}, models.NormalZone},
+		{"cpu danger", models.MetricCPU, []float64{95}, models.DangerZone},
+		{"cpu warning after streak", models.MetricCPU, repeat(80, streak), models.WarningZone},
+		{"cpu short streak", models.MetricCPU, repeat(80, streak-1), models.NormalZone},
+		{"ram danger", models.MetricRAM, repeat(95, 5), models.DangerZone},
+		{"ram normal", models.MetricRAM, repeat(60, 5), models.NormalZone},
+		{"net averaged", models.MetricNet, []float64{100, 0, 0, 0, 0}, models.NormalZone},
+		{"disk danger", models.MetricDisk, repeat(95, 5), models.DangerZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMonitor(configs.DefaultChecker())
+			for _, v := range tt.values {
+				m.record(models.Reading{Metric: tt.metric, Value: v})
+			}
+
+			assert.Equal(t, tt.want, zoneOf(m, tt.metric))
+		})
+	}
+}
+
+func zoneOf(m *Monitor, metric string) models.Zone {
+	u, _ := m.Snapshot().Get(metric)
+	return u.LoadZone
+}
+
+func repeat(v float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+func Test_Monitor_RecordConfiguredThreshold(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.CPU = configs.Threshold{Warning: 30, Danger: 50, Streak: 2}
+	m := NewMonitor(cfg)
+
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 40})
+	assert.Equal(t, models.NormalZone, zoneOf(m, models.MetricCPU))
+
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 40})
+	assert.Equal(t, models.WarningZone, zoneOf(m, models.MetricCPU))
+
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 60})
+	assert.Equal(t, models.DangerZone, zoneOf(m, models.MetricCPU))
+}
+
+func Test_Monitor_ReadinessStaleSample(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.Interval = time.Minute
+	m := NewMonitor(cfg, &fakeCollector{metric: models.MetricCPU, value: 10})
+
+	now := time.Now()
+	m.sampled(models.MetricCPU, now)
+	assert.NoError(t, m.Readiness(now.Add(3*time.Minute)))
+	assert.ErrorContains(t, m.Readiness(now.Add(3*time.Minute+time.Second)), "last sample is older than 3m0s")
+}
+
+// flakyCollector fails the first failures samples and succeeds afterwards.
+type flakyCollector struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyCollector) Name() string {
+	return models.MetricNet
+}
+
+func (f *flakyCollector) Sample(_ context.Context) ([]models.Reading, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("no network data")
+	}
+	return []models.Reading{{Metric: models.MetricNet, Value: 5}}, nil
+}
+
+func (f *flakyCollector) setFailures(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures = n
+}
+
+func Test_Monitor_CollectorRecovers(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.Interval = time.Millisecond
+	cfg.RetryMaxBackoff = 4 * time.Millisecond
+
+	c := &flakyCollector{failures: 3}
+	m := NewMonitor(cfg, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+
+	assert.Eventually(t, func() bool {
+		return zoneOf(m, models.MetricNet) == models.NormalZone
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.exporter.collectorErrors.WithLabelValues(models.MetricNet)))
+	assert.NoError(t, m.Liveness())
+
+	c.setFailures(1000)
+	assert.Eventually(t, func() bool {
+		return zoneOf(m, models.MetricNet) == models.UnknownZone
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, m.Liveness())
+	assert.ErrorContains(t, m.Readiness(time.Now()), "collector net is failing: no network data")
+}
+
+func Test_Backoff(t *testing.T) {
+	tests := []struct {
+		failures   int
+		interval   time.Duration
+		maxBackoff time.Duration
+		want       time.Duration
+	}{
+		{1, time.Second, time.Minute, time.Second},
+		{2, time.Second, time.Minute, 2 * time.Second},
+		{4, time.Second, time.Minute, 8 * time.Second},
+		{10, time.Second, time.Minute, time.Minute},
+		{3, time.Hour, time.Minute, time.Hour},
+		{1, 0, time.Minute, minBackoff},
+		{3, 0, 0, minBackoff},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, backoff(tt.interval, tt.maxBackoff, tt.failures))
+	}
+}
+
+func Test_Monitor_SnapshotIsCopy(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+	m.record(models.Reading{Metric: models.MetricRAM, Value: 60, Raw: map[string]float64{"total_bytes": 100}})
+
+	snap := m.Snapshot()
+	snap.Metrics[1].LoadZone = models.DangerZone
+	snap.Metrics[1].Raw["total_bytes"] = 0
+
+	u, ok := m.Snapshot().Get(models.MetricRAM)
+	require.True(t, ok)
+	assert.Equal(t, models.NormalZone, u.LoadZone)
+	assert.Equal(t, 100.0, u.Raw["total_bytes"])
+}
+
+func Test_Monitor_RecordCoreWarning(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.CPU.Streak = 2
+	cfg.CPUCoreWarning = 90
+	m := NewMonitor(cfg)
+
+	sample := func(core1 float64) {
+		m.record(models.Reading{Metric: models.MetricCPU, Device: "0", Value: 10})
+		m.record(models.Reading{Metric: models.MetricCPU, Device: "1", Value: core1})
+		m.record(models.Reading{Metric: models.MetricCPU, Value: (10 + core1) / 2})
+	}
+
+	sample(100)
+	assert.Equal(t, models.NormalZone, zoneOf(m, models.MetricCPU))
 
-	time.Sleep(time.Millisecond * 5)
+	sample(100)
+	assert.Equal(t, models.WarningZone, zoneOf(m, models.MetricCPU))
+
+	u, _ := m.Snapshot().Get(models.MetricCPU)
+	require.Len(t, u.Devices, 2)
+	assert.Equal(t, "0", u.Devices[0].Device)
+	assert.Equal(t, models.NormalZone, u.Devices[0].LoadZone)
+	assert.Equal(t, "1", u.Devices[1].Device)
+	assert.Equal(t, models.WarningZone, u.Devices[1].LoadZone)
+
+	sample(20)
+	assert.Equal(t, models.NormalZone, zoneOf(m, models.MetricCPU))
+}
+
+func Test_Monitor_RecordCoreWarningDisabled(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.CPU.Streak = 1
+	m := NewMonitor(cfg)
+
+	m.record(models.Reading{Metric: models.MetricCPU, Device: "0", Value: 100})
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 50})
+
+	assert.Equal(t, models.NormalZone, zoneOf(m, models.MetricCPU))
+}
+
+func Test_Monitor_RecordBusiestInterface(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	m := NewMonitor(cfg)
+
+	sample := func(ifaces map[string]float64) {
+		busiest := 0.0
+		for iface, v := range ifaces {
+			m.record(models.Reading{Metric: models.MetricNet, Device: iface, Value: v})
+			busiest = max(busiest, v)
+		}
+		m.record(models.Reading{Metric: models.MetricNet, Value: busiest})
+	}
+
+	for i := 0; i < 5; i++ {
+		sample(map[string]float64{"eth0": 95, "eth1": 1})
+	}
+	assert.Equal(t, models.DangerZone, zoneOf(m, models.MetricNet))
+
+	u, _ := m.Snapshot().Get(models.MetricNet)
+	require.Len(t, u.Devices, 2)
+	assert.Equal(t, models.DangerZone, u.Devices[0].LoadZone)
+	assert.Equal(t, models.NormalZone, u.Devices[1].LoadZone)
+
+	// eth0 disappears and is no longer accounted
+	sample(map[string]float64{"eth1": 1})
+	sample(map[string]float64{"eth1": 1})
+	u, _ = m.Snapshot().Get(models.MetricNet)
+	require.Len(t, u.Devices, 1)
+	assert.Equal(t, "eth1", u.Devices[0].Device)
+}
+
+func Test_Monitor_RecordSaturatedDisk(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+
+	for i := 0; i < 5; i++ {
+		m.record(models.Reading{Metric: models.MetricDisk, Device: "log", Value: 100,
+			Raw: map[string]float64{"queue_length": 12}})
+		m.record(models.Reading{Metric: models.MetricDisk, Device: "data", Value: 2})
+		m.record(models.Reading{Metric: models.MetricDisk, Value: 100})
+	}
+
+	assert.Equal(t, models.DangerZone, zoneOf(m, models.MetricDisk))
+
+	u, _ := m.Snapshot().Get(models.MetricDisk)
+	require.Len(t, u.Devices, 2)
+	assert.Equal(t, "log", u.Devices[1].Device)
+	assert.Equal(t, models.DangerZone, u.Devices[1].LoadZone)
+}
+
+func Test_Monitor_RecordFreeSpace(t *testing.T) {
+	const gib = 1 << 30
+
+	cfg := configs.DefaultChecker()
+	cfg.DiskFree = configs.FreeSpace{WarningPercent: 15, DangerPercent: 5, WarningBytes: 20 * gib}
+	cfg.DiskFreeVolumes = map[string]configs.FreeSpace{"/backup": {DangerPercent: 1}}
+
+	tests := []struct {
+		name   string
+		volume string
+		free   float64
+		size   float64
+		want   models.Zone
+	}{
+		{"normal", "/", 500 * gib, 1000 * gib, models.NormalZone},
+		{"warning percent", "/", 100 * gib, 1000 * gib, models.WarningZone},
+		{"danger percent", "/", 40 * gib, 1000 * gib, models.DangerZone},
+		{"warning bytes", "/", 10 * gib, 40 * gib, models.WarningZone},
+		{"volume override", "/backup", 20 * gib, 1000 * gib, models.NormalZone},
+		{"volume override danger", "/backup", 5 * gib, 1000 * gib, models.DangerZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMonitor(cfg)
+			r := models.Reading{
+				Metric: models.MetricDiskFree,
+				Device: tt.volume,
+				Value:  tt.free / tt.size * 100,
+				Raw:    map[string]float64{"free_bytes": tt.free, "size_bytes": tt.size},
+			}
+			m.record(r)
+			r.Device = ""
+			m.record(r)
+
+			assert.Equal(t, tt.want, zoneOf(m, models.MetricDiskFree))
+		})
+	}
+}
+
+func Test_Monitor_RecordSmoothing(t *testing.T) {
+	spikes := []float64{10, 10, 100, 10, 10}
+
+	tests := []struct {
+		name      string
+		smoothing configs.Smoothing
+		want      models.Zone
+	}{
+		{"sma of a single sample", configs.Smoothing{Method: configs.SmoothingSMA, Window: 1}, models.DangerZone},
+		{"median ignores the spike", configs.Smoothing{Method: configs.SmoothingMedian, Window: 5}, models.NormalZone},
+		{"p95 keeps the spike", configs.Smoothing{Method: configs.SmoothingPercentile, Window: 5, Percentile: 95}, models.DangerZone},
+		{"ema dampens the spike", configs.Smoothing{Method: configs.SmoothingEMA, Alpha: 0.2}, models.NormalZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := configs.DefaultChecker()
+			cfg.Net.Smoothing = tt.smoothing
+			m := NewMonitor(cfg)
+
+			worst := models.NormalZone
+			for _, v := range spikes {
+				m.record(models.Reading{Metric: models.MetricNet, Value: v})
+				worst = max(worst, zoneOf(m, models.MetricNet))
+			}
+
+			assert.Equal(t, tt.want, worst)
+		})
+	}
+}
+
+func Test_Monitor_Transitions(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.CPU.Streak = 1
+	cfg.CPU.Decay = configs.DecayReset
+	cfg.HistorySize = 2
+	m := NewMonitor(cfg)
+	events := m.Subscribe(10)
+
+	for _, v := range []float64{10, 80, 95, 96, 10} {
+		m.record(models.Reading{Metric: models.MetricCPU, Value: v})
+	}
+
+	var got []models.Transition
+	for len(events) > 0 {
+		got = append(got, <-events)
+	}
+	require.Len(t, got, 3)
+	assert.Equal(t, []models.Zone{models.NormalZone, models.WarningZone}, []models.Zone{got[0].From, got[0].To})
+	assert.Equal(t, []models.Zone{models.WarningZone, models.DangerZone}, []models.Zone{got[1].From, got[1].To})
+	assert.Equal(t, []models.Zone{models.DangerZone, models.NormalZone}, []models.Zone{got[2].From, got[2].To})
+	assert.Equal(t, 95.0, got[1].Value)
+	assert.Equal(t, models.MetricCPU, got[1].Metric)
+
+	assert.Equal(t, got[1:], m.History(), "the history keeps the last transitions")
+}
+
+func Test_Monitor_TransitionsOfDevices(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.CPUCoreWarning = 95
+	cfg.CPU.Streak = 1
+	cfg.CPU.Decay = configs.DecayReset
+	m := NewMonitor(cfg)
+	events := m.Subscribe(10)
+
+	for _, core := range []float64{99, 10} {
+		m.record(models.Reading{Metric: models.MetricCPU, Device: "cpu0", Value: core})
+		m.record(models.Reading{Metric: models.MetricCPU, Value: 30})
+	}
+
+	require.Len(t, events, 2)
+	warning, normal := <-events, <-events
+	assert.Equal(t, models.Transition{Metric: models.MetricCPU, From: models.NormalZone, To: models.WarningZone, Value: 30, At: warning.At}, warning)
+	assert.Equal(t, []models.Zone{models.WarningZone, models.NormalZone}, []models.Zone{normal.From, normal.To})
+}
+
+func Test_Monitor_TransitionsSlowSubscriber(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+	events := m.Subscribe(1)
+
+	for _, v := range []float64{95, 10, 95} {
+		m.record(models.Reading{Metric: models.MetricCPU, Value: v})
+	}
+
+	assert.Len(t, events, 1, "transitions beyond the buffer are dropped")
+	assert.Len(t, m.History(), 3)
+}
+
+func Test_Monitor_RecordStoresSamples(t *testing.T) {
+	m := NewMonitor(configs.DefaultChecker())
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 40})
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 60})
+	m.record(models.Reading{Metric: models.MetricDisk, Value: 90, Device: "sda"})
+
+	now := time.Now()
+	points, _, err := m.Store().Query(models.MetricCPU, now.Add(-time.Hour), now, 2*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, 50.0, points[0].Value)
+	assert.Equal(t, 40.0, points[0].Min)
+	assert.Equal(t, 60.0, points[0].Max)
+	assert.Equal(t, uint64(2), points[0].Count)
+
+	points, _, err = m.Store().Query(models.MetricDisk, now.Add(-time.Minute), now, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, points, "the readings of devices are not stored")
+}
+
+type scheduledCollector struct {
+	fakeCollector
+	interval time.Duration
+}
+
+func (s *scheduledCollector) Interval() time.Duration {
+	return s.interval
+}
+
+func Test_Monitor_ScheduledInterval(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	m := NewMonitor(cfg,
+		&scheduledCollector{fakeCollector{metric: models.MetricDiskFree}, time.Hour},
+		&scheduledCollector{fakeCollector{metric: models.MetricDisk}, 0},
+	)
+
+	assert.Equal(t, time.Hour, m.interval(models.MetricDiskFree))
+	assert.Equal(t, cfg.Interval, m.interval(models.MetricDisk), "a zero interval falls back to the check interval")
+}
 
-	assert.NotNil(t, monitor.GetCPUUtilizationValue())
-	assert.NotZero(t, monitor.GetCPUUtilizationValue())
+func Test_Monitor_MaxAgeOfScheduledCollector(t *testing.T) {
+	cfg := configs.DefaultChecker()
+	cfg.MaxAge = 3 * time.Minute
+	cfg.Interval = time.Second
+	m := NewMonitor(cfg,
+		&fakeCollector{metric: models.MetricCPU},
+		&scheduledCollector{fakeCollector{metric: models.MetricDiskFree}, 5 * time.Minute},
+	)
 
-	assert.NotNil(t, monitor.GetRAMUtilizationValue())
-	assert.NotZero(t, monitor.GetRAMUtilizationValue())
+	now := time.Now()
+	m.record(models.Reading{Metric: models.MetricCPU, Value: 10})
+	m.record(models.Reading{Metric: models.MetricDiskFree, Value: 50})
 
-	assert.NotNil(t, monitor.GetNetUtilizationValue())
-	assert.NotZero(t, monitor.GetNetUtilizationValue())
+	cpu, _ := m.Snapshot().Get(models.MetricCPU)
+	assert.Equal(t, models.StaleZone, cpu.ZoneAt(now.Add(4*time.Minute)))
 
-	assert.NotNil(t, monitor.GetDiskUtilizationValue())
-	assert.NotZero(t, monitor.GetDiskUtilizationValue())
+	free, _ := m.Snapshot().Get(models.MetricDiskFree)
+	assert.Equal(t, 15*time.Minute, free.MaxAge, "the max age covers the intervals of the collector")
+	assert.Equal(t, models.NormalZone, free.ZoneAt(now.Add(4*time.Minute)))
 }
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// receiver delivers the zone transitions of the metrics routed to it.
+type receiver interface {
+	// name identifies the receiver in the logs.
+	name() string
+	routes(metric string) bool
+	// run delivers the transitions until ctx is done.
+	run(ctx context.Context, transitions <-chan models.Transition)
+}
+
+// Notifier sends the zone transitions of the metrics to webhooks and
+// Alertmanager.
+type Notifier struct {
+	receivers []receiver
+}
+
+// NewNotifier creates a Notifier sending to the receivers of cfg.
+func NewNotifier(cfg configs.Checker) *Notifier {
+	host, err := os.Hostname()
+	if err != nil {
+		slog.Error("host name is unknown", "error", err)
+	}
+
+	targets := slices.Clone(cfg.Webhooks)
+	for _, u := range cfg.WebhookURLs {
+		targets = append(targets, configs.Webhook{URL: u})
+	}
+
+	n := &Notifier{}
+	for _, t := range targets {
+		n.receivers = append(n.receivers, &webhook{
+			poster:   newPoster(t.URL, cfg),
+			metrics:  t.Metrics,
+			host:     host,
+			renotify: cfg.WebhookRenotify,
+			notified: make(map[string]notification),
+		})
+	}
+	for _, u := range cfg.AlertmanagerURLs {
+		n.receivers = append(n.receivers, &alertmanager{
+			poster: newPoster(strings.TrimSuffix(u, "/")+"/api/v2/alerts", cfg),
+			host:   host,
+			resend: cfg.AlertmanagerResend,
+			firing: make(map[string]alert),
+		})
+	}
+	return n
+}
+
+// Enabled reports whether there is a receiver to notify.
+func (n *Notifier) Enabled() bool {
+	return len(n.receivers) > 0
+}
+
+// Run delivers transitions to the receivers routing their metric until ctx
+// is done. Every receiver is delivered to in order by its own goroutine, so
+// a slow receiver does not delay the others.
+func (n *Notifier) Run(ctx context.Context, transitions <-chan models.Transition) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	queues := make([]chan models.Transition, len(n.receivers))
+	for i, r := range n.receivers {
+		queues[i] = make(chan models.Transition, 100)
+
+		wg.Add(1)
+		go func(r receiver, queue <-chan models.Transition) {
+			defer wg.Done()
+			r.run(ctx, queue)
+		}(r, queues[i])
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-transitions:
+			for i, r := range n.receivers {
+				if !r.routes(t.Metric) {
+					continue
+				}
+
+				select {
+				case queues[i] <- t:
+				default:
+					slog.Warn("zone transition is dropped, the receiver is busy", "receiver", r.name(), "metric", t.Metric)
+				}
+			}
+		}
+	}
+}
+
+// poster posts JSON to a url, retrying failed requests with an exponential
+// backoff.
+type poster struct {
+	url    string
+	client *http.Client
+
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newPoster(url string, cfg configs.Checker) poster {
+	return poster{
+		url:        url,
+		client:     &http.Client{Timeout: cfg.WebhookTimeout},
+		retries:    cfg.WebhookRetries,
+		backoff:    cfg.WebhookBackoff,
+		maxBackoff: cfg.RetryMaxBackoff,
+	}
+}
+
+func (p poster) name() string {
+	return p.url
+}
+
+// deliver posts v encoded to JSON, retrying a failed request.
+func (p poster) deliver(ctx context.Context, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := p.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.retries {
+			return err
+		}
+
+		wait := backoff(p.backoff, p.maxBackoff, attempt+1)
+		slog.Warn("delivery failed", "url", p.url, "error", err, "retry_in", wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// post sends a single request and reports whether a failed one should be
+// retried. Requests rejected by the receiver are not retried, except when it
+// is overloaded.
+func (p poster) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// endpoint records the payloads of type T it receives, failing the first
+// failures requests.
+type endpoint[T any] struct {
+	mu       sync.Mutex
+	payloads []T
+	requests int
+	// path is the path of the last request.
+	path     string
+	failures int
+	status   int
+}
+
+func newEndpoint[T any](t *testing.T) (*endpoint[T], *httptest.Server) {
+	t.Helper()
+
+	r := &endpoint[T]{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.requests++
+		r.path = req.URL.Path
+		if r.requests <= r.failures {
+			w.WriteHeader(r.status)
+			return
+		}
+
+		var p T
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&p))
+		r.payloads = append(r.payloads, p)
+	}))
+	t.Cleanup(srv.Close)
+
+	return r, srv
+}
+
+func (r *endpoint[T]) received() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]T(nil), r.payloads...)
+}
+
+func (r *endpoint[T]) attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.requests
+}
+
+// runNotifier runs a notifier of cfg until the test ends and returns the
+// channel of its transitions.
+func runNotifier(t *testing.T, cfg configs.Checker) chan<- models.Transition {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transitions := make(chan models.Transition)
+	done := make(chan struct{})
+	go func() {
+		NewNotifier(cfg).Run(ctx, transitions)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return transitions
+}
+
+func transition(metric string, from, to models.Zone) models.Transition {
+	return models.Transition{Metric: metric, From: from, To: to, Value: 95, At: time.Now().UTC().Truncate(time.Second)}
+}
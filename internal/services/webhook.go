@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"health-checker/internal/models"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// webhookPayload is the body posted to a webhook. The value and the time
+// are the ones of the transition, also when it is notified again.
+type webhookPayload struct {
+	Host string `json:"host"`
+	models.Transition
+	// Repeated is set when a metric staying in the danger zone is notified
+	// again.
+	Repeated bool `json:"repeated"`
+	// SentAt is the time the notification is sent at.
+	SentAt time.Time `json:"sent_at"`
+}
+
+// notification is the last transition of a metric delivered to a webhook.
+type notification struct {
+	transition models.Transition
+	sentAt     time.Time
+}
+
+// webhook delivers the transitions of the metrics routed to a single url.
+type webhook struct {
+	poster
+	// metrics are the metrics routed to the webhook, every metric when empty.
+	metrics  []string
+	host     string
+	renotify time.Duration
+
+	// notified is the last delivered notification of every metric, a
+	// transition to the notified zone is not delivered again.
+	notified map[string]notification
+}
+
+func (w *webhook) routes(metric string) bool {
+	return len(w.metrics) == 0 || slices.Contains(w.metrics, metric)
+}
+
+func (w *webhook) run(ctx context.Context, transitions <-chan models.Transition) {
+	for {
+		var timer *time.Timer
+		var due <-chan time.Time
+		if wait, ok := w.nextRenotify(time.Now()); ok {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case t := <-transitions:
+			w.notify(ctx, t)
+		case <-due:
+			w.renotifyDanger(ctx, time.Now())
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// notify delivers t unless its zone has already been notified. Metrics
+// that have not been notified yet are considered in the normal zone.
+func (w *webhook) notify(ctx context.Context, t models.Transition) {
+	notified := models.NormalZone
+	if last, ok := w.notified[t.Metric]; ok {
+		notified = last.transition.To
+	}
+	if t.To == notified {
+		slog.Debug("duplicate zone transition is skipped", "url", w.url, "metric", t.Metric, "zone", t.To)
+		return
+	}
+
+	now := time.Now()
+	if err := w.deliver(ctx, webhookPayload{Host: w.host, Transition: t, SentAt: now}); err != nil {
+		slog.Error("webhook delivery failed", "url", w.url, "metric", t.Metric, "error", err)
+		return
+	}
+	w.notified[t.Metric] = notification{transition: t, sentAt: now}
+}
+
+// nextRenotify returns the time left until a metric in the danger zone is
+// due to be notified again, false when there is none.
+func (w *webhook) nextRenotify(now time.Time) (time.Duration, bool) {
+	if w.renotify <= 0 {
+		return 0, false
+	}
+
+	var next time.Time
+	for _, n := range w.notified {
+		if n.transition.To != models.DangerZone {
+			continue
+		}
+		if due := n.sentAt.Add(w.renotify); next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return max(next.Sub(now), 0), true
+}
+
+// renotifyDanger delivers again the transitions to the danger zone notified
+// at least the renotify interval ago.
+func (w *webhook) renotifyDanger(ctx context.Context, now time.Time) {
+	for metric, n := range w.notified {
+		if n.transition.To != models.DangerZone || now.Sub(n.sentAt) < w.renotify {
+			continue
+		}
+
+		err := w.deliver(ctx, webhookPayload{Host: w.host, Transition: n.transition, Repeated: true, SentAt: now})
+		if err != nil {
+			slog.Error("webhook delivery failed", "url", w.url, "metric", metric, "error", err)
+		}
+
+		// a failed delivery is not retried before the next interval either
+		n.sentAt = now
+		w.notified[metric] = n
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"health-checker/internal/configs"
+	"health-checker/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func webhookConfig(webhooks ...configs.Webhook) configs.Checker {
+	cfg := configs.DefaultChecker()
+	cfg.Webhooks = webhooks
+	cfg.WebhookBackoff = time.Millisecond
+	cfg.RetryMaxBackoff = 5 * time.Millisecond
+	cfg.WebhookRenotify = 0
+	return cfg
+}
+
+func Test_Notifier_Routing(t *testing.T) {
+	all, allSrv := newEndpoint[webhookPayload](t)
+	disks, disksSrv := newEndpoint[webhookPayload](t)
+
+	cfg := webhookConfig(configs.Webhook{URL: disksSrv.URL, Metrics: []string{models.MetricDisk, models.MetricDiskFree}})
+	cfg.WebhookURLs = []string{allSrv.URL}
+	transitions := runNotifier(t, cfg)
+
+	cpu := transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+	transitions <- cpu
+	transitions <- transition(models.MetricDiskFree, models.NormalZone, models.WarningZone)
+
+	require.Eventually(t, func() bool {
+		return len(all.received()) == 2 && len(disks.received()) == 1
+	}, time.Second, time.Millisecond)
+
+	got := all.received()[0]
+	assert.Equal(t, cpu, got.Transition)
+	assert.NotEmpty(t, got.Host)
+	assert.False(t, got.Repeated)
+	assert.False(t, got.SentAt.Before(cpu.At))
+	assert.Equal(t, models.MetricDiskFree, disks.received()[0].Metric)
+}
+
+func Test_Notifier_Retry(t *testing.T) {
+	r, srv := newEndpoint[webhookPayload](t)
+	r.failures = 2
+	transitions := runNotifier(t, webhookConfig(configs.Webhook{URL: srv.URL}))
+
+	transitions <- transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, r.attempts())
+}
+
+func Test_Notifier_RetriesExhausted(t *testing.T) {
+	r, srv := newEndpoint[webhookPayload](t)
+	r.failures = 10
+	cfg := webhookConfig(configs.Webhook{URL: srv.URL})
+	cfg.WebhookRetries = 1
+	transitions := runNotifier(t, cfg)
+
+	transitions <- transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+	require.Eventually(t, func() bool {
+		return r.attempts() == 2
+	}, time.Second, time.Millisecond)
+
+	// the danger zone has not been delivered, so its end is not either
+	transitions <- transition(models.MetricCPU, models.DangerZone, models.NormalZone)
+	transitions <- transition(models.MetricRAM, models.NormalZone, models.WarningZone)
+	require.Eventually(t, func() bool {
+		return r.attempts() == 4
+	}, time.Second, time.Millisecond)
+}
+
+func Test_Notifier_NotRetriedWhenRejected(t *testing.T) {
+	r, srv := newEndpoint[webhookPayload](t)
+	r.failures, r.status = 1, http.StatusBadRequest
+	transitions := runNotifier(t, webhookConfig(configs.Webhook{URL: srv.URL}))
+
+	transitions <- transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+	transitions <- transition(models.MetricRAM, models.NormalZone, models.DangerZone)
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, models.MetricRAM, r.received()[0].Metric)
+	assert.Equal(t, 2, r.attempts())
+}
+
+func Test_Notifier_Deduplication(t *testing.T) {
+	r, srv := newEndpoint[webhookPayload](t)
+	transitions := runNotifier(t, webhookConfig(configs.Webhook{URL: srv.URL}))
+
+	transitions <- transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+	transitions <- transition(models.MetricCPU, models.StaleZone, models.DangerZone)
+	transitions <- transition(models.MetricCPU, models.DangerZone, models.NormalZone)
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, models.NormalZone, r.received()[1].To)
+	assert.Equal(t, 2, r.attempts())
+}
+
+func Test_Notifier_Renotify(t *testing.T) {
+	r, srv := newEndpoint[webhookPayload](t)
+	cfg := webhookConfig(configs.Webhook{URL: srv.URL})
+	cfg.WebhookRenotify = 10 * time.Millisecond
+	transitions := runNotifier(t, cfg)
+
+	danger := transition(models.MetricCPU, models.NormalZone, models.DangerZone)
+	transitions <- danger
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) >= 3
+	}, time.Second, time.Millisecond)
+	repeated := r.received()[1]
+	assert.True(t, repeated.Repeated)
+	assert.Equal(t, danger, repeated.Transition, "the value and the time are the ones of the transition")
+	assert.True(t, repeated.SentAt.After(r.received()[0].SentAt))
+
+	transitions <- transition(models.MetricCPU, models.DangerZone, models.WarningZone)
+	require.Eventually(t, func() bool {
+		payloads := r.received()
+		return payloads[len(payloads)-1].To == models.WarningZone
+	}, time.Second, time.Millisecond)
+
+	sent := len(r.received())
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, r.received(), sent, "the warning zone is not notified again")
+}
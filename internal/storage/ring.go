@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket aggregates the samples of a metric starting at start. A raw sample
+// is a bucket of a single sample.
+type bucket struct {
+	start time.Time
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func sampleBucket(at time.Time, value float64) bucket {
+	return bucket{start: at, count: 1, sum: value, min: value, max: value}
+}
+
+// merge adds the samples of o to b.
+func (b *bucket) merge(o bucket) {
+	if b.count == 0 {
+		*b = bucket{start: b.start, count: o.count, sum: o.sum, min: o.min, max: o.max}
+		return
+	}
+
+	b.count += o.count
+	b.sum += o.sum
+	b.min = math.Min(b.min, o.min)
+	b.max = math.Max(b.max, o.max)
+}
+
+const (
+	// bucketSize is the size of an encoded bucket: the start in unix
+	// nanoseconds, the count, the sum, the min and the max.
+	bucketSize = 5 * 8
+	// headerSize is the size of the header of a ring: the magic, the
+	// capacity, the position of the next bucket and the number of buckets.
+	headerSize = 4 + 4 + 8 + 8
+)
+
+var magic = [4]byte{'h', 'c', 't', 's'}
+
+func (b bucket) encode(buf []byte) {
+	binary.LittleEndian.PutUint64(buf[0:], uint64(b.start.UnixNano()))
+	binary.LittleEndian.PutUint64(buf[8:], b.count)
+	binary.LittleEndian.PutUint64(buf[16:], math.Float64bits(b.sum))
+	binary.LittleEndian.PutUint64(buf[24:], math.Float64bits(b.min))
+	binary.LittleEndian.PutUint64(buf[32:], math.Float64bits(b.max))
+}
+
+func decodeBucket(buf []byte) bucket {
+	return bucket{
+		start: time.Unix(0, int64(binary.LittleEndian.Uint64(buf[0:]))),
+		count: binary.LittleEndian.Uint64(buf[8:]),
+		sum:   math.Float64frombits(binary.LittleEndian.Uint64(buf[16:])),
+		min:   math.Float64frombits(binary.LittleEndian.Uint64(buf[24:])),
+		max:   math.Float64frombits(binary.LittleEndian.Uint64(buf[32:])),
+	}
+}
+
+// backing is the storage of a ring, a file or memory.
+type backing interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// ring keeps the last capacity buckets of a backing, from the oldest to the
+// newest. The header is written after every bucket, so the ring is restored
+// when the backing is opened again.
+type ring struct {
+	f        backing
+	capacity int
+	// next is the position the next bucket is written at.
+	next  int
+	count int
+	// last is the newest bucket, valid when count > 0.
+	last bucket
+}
+
+// openRing restores a ring of capacity buckets from f, an empty or a
+// mismatching backing is reset.
+func openRing(f backing, capacity int) (*ring, error) {
+	r := &ring{f: f, capacity: capacity}
+
+	header := make([]byte, headerSize)
+	_, err := f.ReadAt(header, 0)
+	switch {
+	case errors.Is(err, io.EOF):
+		return r, r.writeHeader()
+	case err != nil:
+		return nil, err
+	}
+
+	if [4]byte(header[:4]) != magic || int(binary.LittleEndian.Uint32(header[4:])) != capacity {
+		return r, r.writeHeader()
+	}
+
+	r.next = int(binary.LittleEndian.Uint64(header[8:]))
+	r.count = int(binary.LittleEndian.Uint64(header[16:]))
+	if r.next >= capacity || r.count > capacity {
+		return nil, fmt.Errorf("corrupted header: next %d, count %d, capacity %d", r.next, r.count, capacity)
+	}
+
+	if r.count > 0 {
+		buf := make([]byte, bucketSize)
+		if _, err := f.ReadAt(buf, r.offset(r.prev(r.next))); err != nil {
+			return nil, err
+		}
+		r.last = decodeBucket(buf)
+	}
+	return r, nil
+}
+
+func (r *ring) offset(pos int) int64 {
+	return headerSize + int64(pos)*bucketSize
+}
+
+func (r *ring) prev(pos int) int {
+	return (pos - 1 + r.capacity) % r.capacity
+}
+
+func (r *ring) writeHeader() error {
+	header := make([]byte, headerSize)
+	copy(header, magic[:])
+	binary.LittleEndian.PutUint32(header[4:], uint32(r.capacity))
+	binary.LittleEndian.PutUint64(header[8:], uint64(r.next))
+	binary.LittleEndian.PutUint64(header[16:], uint64(r.count))
+
+	_, err := r.f.WriteAt(header, 0)
+	return err
+}
+
+func (r *ring) write(pos int, b bucket) error {
+	buf := make([]byte, bucketSize)
+	b.encode(buf)
+
+	_, err := r.f.WriteAt(buf, r.offset(pos))
+	return err
+}
+
+// append adds b as the newest bucket, overwriting the oldest one when the
+// ring is full.
+func (r *ring) append(b bucket) error {
+	if err := r.write(r.next, b); err != nil {
+		return err
+	}
+
+	r.next = (r.next + 1) % r.capacity
+	r.count = min(r.count+1, r.capacity)
+	r.last = b
+	return r.writeHeader()
+}
+
+// replaceLast overwrites the newest bucket with b.
+func (r *ring) replaceLast(b bucket) error {
+	if r.count == 0 {
+		return r.append(b)
+	}
+
+	if err := r.write(r.prev(r.next), b); err != nil {
+		return err
+	}
+	r.last = b
+	return nil
+}
+
+// covers reports whether the ring still keeps the buckets starting at from,
+// that is it has not overwritten a bucket yet or its oldest bucket does not
+// start after from.
+func (r *ring) covers(from time.Time) (bool, error) {
+	if r.count < r.capacity {
+		return true, nil
+	}
+
+	// a full ring overwrites its oldest bucket next
+	buf := make([]byte, bucketSize)
+	if _, err := r.f.ReadAt(buf, r.offset(r.next)); err != nil {
+		return false, err
+	}
+	return !decodeBucket(buf).start.After(from), nil
+}
+
+// buckets returns the buckets starting within [from, to] from the oldest to
+// the newest.
+func (r *ring) buckets(from, to time.Time) ([]bucket, error) {
+	if r.count == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, r.capacity*bucketSize)
+	if _, err := r.f.ReadAt(buf, headerSize); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	oldest := (r.next - r.count + r.capacity) % r.capacity
+	var buckets []bucket
+	for i := 0; i < r.count; i++ {
+		pos := (oldest + i) % r.capacity
+		b := decodeBucket(buf[pos*bucketSize:])
+		if b.start.Before(from) || b.start.After(to) {
+			continue
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// memory is a backing in memory growing as it is written.
+type memory struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (m *memory) ReadAt(p []byte, off int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *memory) WriteAt(p []byte, off int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if end := int(off) + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	return copy(m.data[off:], p), nil
+}
+
+func (m *memory) Close() error {
+	return nil
+}
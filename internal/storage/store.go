@@ -0,0 +1,248 @@
+// Package storage keeps the samples of the metrics in bounded rings of raw
+// samples and of 1m, 5m and 1h rollups, in memory or on disk.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Resolution is a ring of buckets of a metric.
+type Resolution struct {
+	Name string
+	// Step is the duration of a bucket, zero for the raw samples.
+	Step time.Duration
+	// capacity is the number of buckets kept, zero for the configured
+	// number of raw samples.
+	capacity int
+}
+
+// resolutions are the resolutions kept for every metric, from the finest to
+// the coarsest.
+var resolutions = []Resolution{
+	{Name: "raw"},
+	{Name: "1m", Step: time.Minute, capacity: 7 * 24 * 60},
+	{Name: "5m", Step: 5 * time.Minute, capacity: 30 * 24 * 12},
+	{Name: "1h", Step: time.Hour, capacity: 365 * 24},
+}
+
+// Point is a bucket of a range query.
+type Point struct {
+	Time time.Time `json:"time"`
+	// Value is the mean of the samples within the bucket.
+	Value float64 `json:"value"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count uint64  `json:"count"`
+}
+
+// Store keeps the samples of the metrics. It is safe for concurrent use.
+type Store struct {
+	// dir is the directory of the rings, they are kept in memory when empty.
+	dir        string
+	rawSamples int
+
+	mu     sync.Mutex
+	series map[string][]*ring
+	closed bool
+}
+
+// NewMemory creates a store keeping rawSamples raw samples of every metric in
+// memory.
+func NewMemory(rawSamples int) *Store {
+	return &Store{rawSamples: max(rawSamples, 1), series: make(map[string][]*ring)}
+}
+
+// Open creates a store keeping rawSamples raw samples of every metric in
+// files in dir, restoring the samples kept there before.
+func Open(dir string, rawSamples int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+
+	s := NewMemory(rawSamples)
+	s.dir = dir
+	return s, nil
+}
+
+// rings returns the rings of metric by resolution, opened on first use.
+// It must be called with mu held.
+func (s *Store) rings(metric string) ([]*ring, error) {
+	if s.closed {
+		return nil, errors.New("storage is closed")
+	}
+	if rings, ok := s.series[metric]; ok {
+		return rings, nil
+	}
+
+	rings := make([]*ring, 0, len(resolutions))
+	for _, res := range resolutions {
+		capacity := res.capacity
+		if capacity == 0 {
+			capacity = s.rawSamples
+		}
+
+		var f backing = &memory{}
+		if s.dir != "" {
+			file, err := os.OpenFile(filepath.Join(s.dir, metric+"."+res.Name), os.O_RDWR|os.O_CREATE, 0o644)
+			if err != nil {
+				closeRings(rings)
+				return nil, fmt.Errorf("storage: %w", err)
+			}
+			f = file
+		}
+
+		r, err := openRing(f, capacity)
+		if err != nil {
+			f.Close()
+			closeRings(rings)
+			return nil, fmt.Errorf("storage: %s %s: %w", metric, res.Name, err)
+		}
+		rings = append(rings, r)
+	}
+
+	s.series[metric] = rings
+	return rings, nil
+}
+
+func closeRings(rings []*ring) error {
+	var errs []error
+	for _, r := range rings {
+		errs = append(errs, r.f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Add stores value of metric sampled at at as a raw sample and adds it to
+// the rollup buckets it falls in.
+func (s *Store) Add(metric string, at time.Time, value float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rings, err := s.rings(metric)
+	if err != nil {
+		return err
+	}
+
+	sample := sampleBucket(at, value)
+	errs := []error{rings[0].append(sample)}
+
+	for i, res := range resolutions[1:] {
+		r := rings[i+1]
+		start := at.Truncate(res.Step)
+
+		// a sample older than the newest bucket, e.g. after the clock was
+		// set back, is added to the newest bucket
+		if r.count > 0 && !start.After(r.last.start) {
+			b := r.last
+			b.merge(sample)
+			errs = append(errs, r.replaceLast(b))
+			continue
+		}
+
+		b := bucket{start: start}
+		b.merge(sample)
+		errs = append(errs, r.append(b))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("storage: %s: %w", metric, err)
+	}
+	return nil
+}
+
+// Query returns the samples of metric within [from, to] aggregated into
+// buckets of step, read from the coarsest resolution whose step does not
+// exceed step. When that resolution no longer keeps the samples at from, the
+// finest coarser one that still does is read instead, the coarsest one when
+// none does.
+func (s *Store) Query(metric string, from, to time.Time, step time.Duration) ([]Point, Resolution, error) {
+	if step <= 0 {
+		return nil, Resolution{}, fmt.Errorf("step must be > 0, got %s", step)
+	}
+
+	idx := 0
+	for i, res := range resolutions {
+		if res.Step > 0 && res.Step <= step {
+			idx = i
+		}
+	}
+
+	buckets, idx, err := s.buckets(metric, idx, from, to)
+	res := resolutions[idx]
+	if err != nil {
+		return nil, res, err
+	}
+
+	byStart := make(map[time.Time]*bucket)
+	for _, b := range buckets {
+		start := b.start.Truncate(step)
+		agg, ok := byStart[start]
+		if !ok {
+			agg = &bucket{start: start}
+			byStart[start] = agg
+		}
+		agg.merge(b)
+	}
+
+	points := make([]Point, 0, len(byStart))
+	for start, b := range byStart {
+		points = append(points, Point{
+			Time:  start,
+			Value: b.sum / float64(b.count),
+			Min:   b.min,
+			Max:   b.max,
+			Count: b.count,
+		})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return points, res, nil
+}
+
+// buckets returns the buckets of metric within [from, to] and the index of
+// their resolution, the finest resolution starting at idx that covers from.
+func (s *Store) buckets(metric string, idx int, from, to time.Time) ([]bucket, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rings, err := s.rings(metric)
+	if err != nil {
+		return nil, idx, err
+	}
+
+	for ; idx < len(resolutions)-1; idx++ {
+		covers, err := rings[idx].covers(from.Truncate(resolutions[idx].Step))
+		if err != nil {
+			return nil, idx, fmt.Errorf("storage: %s %s: %w", metric, resolutions[idx].Name, err)
+		}
+		if covers {
+			break
+		}
+	}
+
+	buckets, err := rings[idx].buckets(from.Truncate(resolutions[idx].Step), to)
+	return buckets, idx, err
+}
+
+// Close closes the files of the store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	var errs []error
+	for metric, rings := range s.series {
+		errs = append(errs, closeRings(rings))
+		delete(s.series, metric)
+	}
+	return errors.Join(errs...)
+}
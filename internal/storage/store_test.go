@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func values(points []Point) []float64 {
+	var v []float64
+	for _, p := range points {
+		v = append(v, p.Value)
+	}
+	return v
+}
+
+func Test_Store_Raw(t *testing.T) {
+	s := NewMemory(3)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Add("cpu", epoch.Add(time.Duration(i)*time.Second), float64(i)))
+	}
+
+	points, res, err := s.Query("cpu", epoch.Add(2*time.Second), epoch.Add(time.Minute), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "raw", res.Name)
+	assert.Equal(t, []float64{2, 3, 4}, values(points))
+	assert.True(t, points[0].Time.Equal(epoch.Add(2*time.Second)))
+
+	_, res, err = s.Query("cpu", epoch, epoch.Add(time.Minute), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "1m", res.Name, "the oldest samples are dropped")
+}
+
+func Test_Store_Rollups(t *testing.T) {
+	s := NewMemory(1)
+	// a sample every 30 seconds for two hours, the value is the minute
+	for i := 0; i < 240; i++ {
+		require.NoError(t, s.Add("cpu", epoch.Add(time.Duration(i)*30*time.Second), float64(i/2)))
+	}
+
+	tests := []struct {
+		name       string
+		step       time.Duration
+		resolution string
+		points     int
+		first      Point
+	}{
+		{"1m", time.Minute, "1m", 120, Point{Value: 0, Min: 0, Max: 0, Count: 2}},
+		{"2m from 1m", 2 * time.Minute, "1m", 60, Point{Value: 0.5, Min: 0, Max: 1, Count: 4}},
+		{"5m", 5 * time.Minute, "5m", 24, Point{Value: 2, Min: 0, Max: 4, Count: 10}},
+		{"1h", time.Hour, "1h", 2, Point{Value: 29.5, Min: 0, Max: 59, Count: 120}},
+		{"1d from 1h", 24 * time.Hour, "1h", 1, Point{Value: 59.5, Min: 0, Max: 119, Count: 240}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, res, err := s.Query("cpu", epoch, epoch.Add(3*time.Hour), tt.step)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resolution, res.Name)
+			require.Len(t, points, tt.points)
+
+			first := points[0]
+			first.Time = time.Time{}
+			assert.Equal(t, tt.first, first)
+		})
+	}
+}
+
+func Test_Store_QueryRange(t *testing.T) {
+	s := NewMemory(100)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Add("ram", epoch.Add(time.Duration(i)*time.Minute), float64(i)))
+	}
+	require.NoError(t, s.Add("cpu", epoch, 99))
+
+	points, _, err := s.Query("ram", epoch.Add(3*time.Minute), epoch.Add(5*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 4, 5}, values(points))
+
+	points, _, err = s.Query("net", epoch, epoch.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	_, _, err = s.Query("ram", epoch, epoch.Add(time.Hour), 0)
+	assert.Error(t, err)
+}
+
+func Test_Store_QueryBeyondRetention(t *testing.T) {
+	s := NewMemory(60)
+	// a sample every minute for ten days, longer than the 1m rollups are kept
+	for i := 0; i < 10*24*60; i++ {
+		require.NoError(t, s.Add("cpu", epoch.Add(time.Duration(i)*time.Minute), 1))
+	}
+
+	tests := []struct {
+		name       string
+		from       time.Time
+		step       time.Duration
+		resolution string
+		points     int
+	}{
+		{"recent raw samples", epoch.Add(10*24*time.Hour - 30*time.Minute), time.Second, "raw", 30},
+		{"raw samples overwritten", epoch.Add(9 * 24 * time.Hour), time.Second, "1m", 60},
+		{"1m rollups overwritten", epoch, time.Minute, "5m", 12},
+		{"5m step", epoch, 5 * time.Minute, "5m", 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, res, err := s.Query("cpu", tt.from, tt.from.Add(time.Hour-time.Nanosecond), tt.step)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resolution, res.Name)
+			assert.Len(t, points, tt.points)
+		})
+	}
+
+	// the first two days are only kept by the 5m rollups
+	points, res, err := s.Query("cpu", epoch, epoch.Add(48*time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "5m", res.Name)
+	assert.Len(t, points, 577)
+}
+
+func Test_Store_ClockSetBack(t *testing.T) {
+	s := NewMemory(10)
+	require.NoError(t, s.Add("cpu", epoch.Add(2*time.Minute), 10))
+	require.NoError(t, s.Add("cpu", epoch, 20))
+
+	points, _, err := s.Query("cpu", epoch, epoch.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, 15.0, points[0].Value)
+}
+
+func Test_Store_Reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, 5)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, s.Add("disk", epoch.Add(time.Duration(i)*20*time.Second), float64(i)))
+	}
+	require.NoError(t, s.Close())
+
+	s, err = Open(dir, 5)
+	require.NoError(t, err)
+	defer s.Close()
+
+	points, _, err := s.Query("disk", epoch.Add(40*time.Second), epoch.Add(time.Hour), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3, 4, 5, 6}, values(points))
+
+	// the open minute bucket is continued after the restart
+	require.NoError(t, s.Add("disk", epoch.Add(150*time.Second), 9))
+	points, _, err = s.Query("disk", epoch, epoch.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 4, 7.5}, values(points))
+	assert.Equal(t, uint64(2), points[2].Count)
+}
+
+func Test_Store_ReopenWithOtherCapacity(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, 5)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("cpu", epoch, 1))
+	require.NoError(t, s.Close())
+
+	s, err = Open(dir, 10)
+	require.NoError(t, err)
+	defer s.Close()
+
+	points, _, err := s.Query("cpu", epoch, epoch.Add(time.Hour), time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, points, "the raw samples are reset")
+
+	points, _, err = s.Query("cpu", epoch, epoch.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, points, 1, "the rollups are kept")
+}
+
+func Test_Store_Bounded(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, 10)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.Add("cpu", epoch.Add(time.Duration(i)*time.Second), 1))
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "cpu.raw"))
+	require.NoError(t, err)
+	assert.EqualValues(t, headerSize+10*bucketSize, info.Size())
+}